@@ -8,6 +8,7 @@ require (
 	github.com/disgoorg/disgo v0.16.8
 	github.com/docker/distribution v2.8.2+incompatible
 	github.com/docker/docker v24.0.5+incompatible
+	github.com/eclipse/paho.mqtt.golang v1.4.3
 	github.com/opencontainers/go-digest v1.0.0
 	github.com/rs/zerolog v1.30.1-0.20230802082739-7d5aa987d03a
 	golang.org/x/exp v0.0.0-20230817173708-d852ddb80c63
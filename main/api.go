@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/config"
+	"github.com/terrails/yacu/types/database"
+)
+
+type pendingUpdateResponse struct {
+	Id        string    `json:"id"`
+	Container string    `json:"container"`
+	Image     string    `json:"image"`
+	Digest    string    `json:"digest"`
+	Status    string    `json:"status"`
+	Created   time.Time `json:"created"`
+	Updated   time.Time `json:"updated"`
+}
+
+type quarantineResponse struct {
+	Container   string    `json:"container"`
+	Image       string    `json:"image"`
+	Digest      string    `json:"digest"`
+	Failures    int       `json:"failures"`
+	LastError   string    `json:"last_error"`
+	FirstFailed time.Time `json:"first_failed"`
+	LastFailed  time.Time `json:"last_failed"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// serves the http api until the listener fails, should be run in its own goroutine
+func (app Yacu) ServeApi(ctx context.Context, api config.Api) {
+	logger := zerolog.Ctx(ctx).With().Str("service", "api").Str("listen", api.Listen).Logger()
+	ctx = logger.WithContext(context.Background())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/updates", app.authorized(api, func(w http.ResponseWriter, r *http.Request) {
+		app.handlePendingUpdates(ctx, w, r)
+	}))
+	mux.HandleFunc("/api/updates/", app.authorized(api, func(w http.ResponseWriter, r *http.Request) {
+		app.handleResolveUpdate(ctx, w, r)
+	}))
+	mux.HandleFunc("/api/quarantine", app.authorized(api, func(w http.ResponseWriter, r *http.Request) {
+		app.handleQuarantined(ctx, w, r)
+	}))
+	mux.HandleFunc("/api/quarantine/", app.authorized(api, func(w http.ResponseWriter, r *http.Request) {
+		app.handleClearQuarantine(ctx, w, r)
+	}))
+
+	server := &http.Server{
+		Addr:              api.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	logger.Info().Msg("API listening")
+	if err := server.ListenAndServe(); err != nil {
+		logger.Err(err).Msg("API server stopped")
+	}
+}
+
+func (app Yacu) authorized(api config.Api, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(api.Token) > 0 {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(api.Token)) != 1 {
+				writeJson(w, http.StatusUnauthorized, errorResponse{Error: "unauthorized"})
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// GET /api/updates
+func (app Yacu) handlePendingUpdates(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	updates, err := app.DB.GetPendingUpdates(database.PENDING_WAITING, database.PENDING_APPROVED)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Fetching pending updates failed")
+		writeJson(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	response := []pendingUpdateResponse{}
+	for _, update := range updates {
+		response = append(response, newPendingUpdateResponse(update))
+	}
+	writeJson(w, http.StatusOK, response)
+}
+
+// POST /api/updates/<id>/approve and POST /api/updates/<id>/reject
+func (app Yacu) handleResolveUpdate(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/updates/"), "/"), "/")
+	if len(parts) != 2 || (parts[1] != "approve" && parts[1] != "reject") {
+		writeJson(w, http.StatusNotFound, errorResponse{Error: "not found"})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeJson(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	approve := parts[1] == "approve"
+	pending, err := app.ResolvePending(ctx, parts[0], approve)
+	if err != nil {
+		writeJson(w, http.StatusConflict, errorResponse{Error: err.Error()})
+		return
+	}
+
+	if approve && app.Approval.ApplyImmediately {
+		go app.RunApproved(ctx)
+	}
+
+	writeJson(w, http.StatusOK, newPendingUpdateResponse(pending))
+}
+
+// GET /api/quarantine
+func (app Yacu) handleQuarantined(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJson(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	entries, err := app.DB.GetQuarantinedContainers()
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Msg("Fetching quarantined containers failed")
+		writeJson(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	response := []quarantineResponse{}
+	for _, entry := range entries {
+		response = append(response, quarantineResponse{
+			Container:   entry.Container,
+			Image:       entry.Image,
+			Digest:      entry.Digest.String(),
+			Failures:    entry.Count,
+			LastError:   entry.LastError,
+			FirstFailed: entry.FirstFailed,
+			LastFailed:  entry.LastFailed,
+		})
+	}
+	writeJson(w, http.StatusOK, response)
+}
+
+// DELETE /api/quarantine/<container>
+func (app Yacu) handleClearQuarantine(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/quarantine/"), "/")
+	if len(name) == 0 || strings.Contains(name, "/") {
+		writeJson(w, http.StatusNotFound, errorResponse{Error: "not found"})
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		writeJson(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return
+	}
+
+	found, err := app.ClearQuarantine(ctx, name)
+	if err != nil {
+		writeJson(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	if !found {
+		writeJson(w, http.StatusNotFound, errorResponse{Error: "container is not quarantined"})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newPendingUpdateResponse(update *database.PendingUpdateRow) pendingUpdateResponse {
+	return pendingUpdateResponse{
+		Id:        update.Token,
+		Container: update.Container,
+		Image:     update.Image,
+		Digest:    update.Digest.String(),
+		Status:    string(update.Status),
+		Created:   update.Created,
+		Updated:   update.Updated,
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
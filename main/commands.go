@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/terrails/yacu/types/backup"
+	"github.com/terrails/yacu/types/database"
+)
+
+const commandUsage = `usage: yacu [-config path] [command]
+
+commands:
+  pending         list updates waiting for approval
+  approve <id>    approve a pending update
+  reject <id>     reject a pending update
+  history [name]  list recent updates, optionally only of the given container
+  backups <name>  list volume backups of a container
+  restore-volumes <name> <backup>
+                  restore the volumes of a container from a backup
+  quarantine      list containers whose updates are skipped after failing repeatedly
+  quarantine clear <name>
+                  clear the quarantine of a container
+  outbox          list queued and failed notifications
+  outbox retry [id]
+                  queue failed notifications again, all of them if no id is given
+
+Runs the scheduled updater when no command is given.`
+
+// runs a one-off command and returns the process exit code
+func (app Yacu) RunCommand(ctx context.Context, args []string) int {
+	switch args[0] {
+	case "pending":
+		return app.pendingCommand()
+	case "approve", "reject":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, commandUsage)
+			return 2
+		}
+		return app.resolveCommand(ctx, args[1], args[0] == "approve")
+	case "history":
+		container := ""
+		if len(args) > 1 {
+			container = strings.TrimPrefix(args[1], "/")
+		}
+		return app.historyCommand(container)
+	case "backups":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, commandUsage)
+			return 2
+		}
+		return app.backupsCommand(strings.TrimPrefix(args[1], "/"))
+	case "restore-volumes":
+		if len(args) != 3 {
+			fmt.Fprintln(os.Stderr, commandUsage)
+			return 2
+		}
+		return app.restoreCommand(ctx, strings.TrimPrefix(args[1], "/"), args[2])
+	case "quarantine":
+		if len(args) == 1 {
+			return app.quarantineCommand()
+		}
+		if args[1] != "clear" || len(args) != 3 {
+			fmt.Fprintln(os.Stderr, commandUsage)
+			return 2
+		}
+		return app.clearQuarantineCommand(ctx, strings.TrimPrefix(args[2], "/"))
+	case "outbox":
+		if len(args) == 1 {
+			return app.outboxCommand()
+		}
+		if args[1] != "retry" || len(args) > 3 {
+			fmt.Fprintln(os.Stderr, commandUsage)
+			return 2
+		}
+		id := ""
+		if len(args) == 3 {
+			id = args[2]
+		}
+		return app.retryOutboxCommand(id)
+	default:
+		fmt.Fprintln(os.Stderr, commandUsage)
+		return 2
+	}
+}
+
+func (app Yacu) pendingCommand() int {
+	updates, err := app.DB.GetPendingUpdates(database.PENDING_WAITING, database.PENDING_APPROVED)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fetching pending updates failed: %v\n", err)
+		return 1
+	}
+
+	if len(updates) == 0 {
+		fmt.Println("No pending updates")
+		return 0
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tCONTAINER\tIMAGE\tDIGEST\tSTATUS\tFOUND")
+	for _, update := range updates {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			update.Token,
+			update.Container,
+			update.Image,
+			update.Digest.Encoded()[:12],
+			update.Status,
+			update.Created.Local().Format(time.DateTime),
+		)
+	}
+	writer.Flush()
+	return 0
+}
+
+func (app Yacu) resolveCommand(ctx context.Context, token string, approve bool) int {
+	pending, err := app.ResolvePending(ctx, token, approve)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("Update of %s to %s %s\n", pending.Container, pending.Digest.Encoded()[:12], pending.Status)
+
+	if approve && app.Approval.ApplyImmediately {
+		app.RunApproved(ctx)
+	}
+	return 0
+}
+
+func (app Yacu) historyCommand(container string) int {
+	entries, err := app.DB.GetUpdateHistory(container, 20)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fetching update history failed: %v\n", err)
+		return 1
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No updates found")
+		return 0
+	}
+
+	for _, entry := range entries {
+		fmt.Printf("%s  %s (%s) %s in %s\n",
+			entry.Started.Local().Format(time.DateTime),
+			entry.Container,
+			entry.Image,
+			entry.Status,
+			entry.Finished.Sub(entry.Started).Round(time.Second),
+		)
+		if len(entry.Message) > 0 {
+			fmt.Printf("  error: %s\n", entry.Message)
+		}
+		if len(entry.Backup) > 0 {
+			fmt.Printf("  volume backup: %s\n", entry.Backup)
+		}
+		for _, hook := range entry.Hooks {
+			fmt.Printf("  %s hook %q exited with code %d\n", hook.Name, hook.Command, hook.ExitCode)
+			for _, line := range strings.Split(strings.TrimSpace(hook.Output), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+	return 0
+}
+
+func (app Yacu) backupsCommand(container string) int {
+	backups, err := backup.List(app.Backup, container)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "listing backups failed: %v\n", err)
+		return 1
+	}
+
+	if len(backups) == 0 {
+		fmt.Printf("No backups of %s found\n", container)
+		return 0
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "BACKUP\tIMAGE\tVOLUMES")
+	for _, entry := range backups {
+		volumes := []string{}
+		for _, volume := range entry.Volumes {
+			volumes = append(volumes, volume.Destination)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", entry.Id, entry.Image, strings.Join(volumes, ", "))
+	}
+	writer.Flush()
+	return 0
+}
+
+func (app Yacu) restoreCommand(ctx context.Context, name, id string) int {
+	// a run of the daemon could stop, back up or recreate the same container meanwhile
+	if err := app.runLock.Lock(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer app.runLock.Unlock()
+
+	data, err := app.Client.ContainerInspect(context.Background(), name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "inspecting container %s failed: %v\n", name, err)
+		return 1
+	}
+
+	running := data.State.Running
+	if running {
+		fmt.Printf("Stopping %s\n", name)
+		if err := app.Client.ContainerStop(context.Background(), data.ID, container.StopOptions{Timeout: &app.Updater.StopTimeout}); err != nil {
+			fmt.Fprintf(os.Stderr, "stopping container %s failed: %v\n", name, err)
+			return 1
+		}
+	}
+
+	restoreErr := backup.Restore(ctx, app.Client, app.Backup, &data, id)
+	if restoreErr != nil {
+		fmt.Fprintln(os.Stderr, restoreErr)
+	} else {
+		fmt.Printf("Restored volumes of %s from %s\n", name, id)
+	}
+
+	if running {
+		fmt.Printf("Starting %s\n", name)
+		if err := app.Client.ContainerStart(context.Background(), data.ID, types.ContainerStartOptions{}); err != nil {
+			fmt.Fprintf(os.Stderr, "starting container %s failed: %v\n", name, err)
+			return 1
+		}
+	}
+
+	if restoreErr != nil {
+		return 1
+	}
+	return 0
+}
+
+func (app Yacu) quarantineCommand() int {
+	entries, err := app.DB.GetQuarantinedContainers()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fetching quarantined containers failed: %v\n", err)
+		return 1
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No quarantined containers")
+		return 0
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "CONTAINER\tIMAGE\tDIGEST\tFAILURES\tLAST FAILURE\tLAST ERROR")
+	for _, entry := range entries {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			entry.Container,
+			entry.Image,
+			entry.Digest.Encoded()[:12],
+			entry.Count,
+			entry.LastFailed.Local().Format(time.DateTime),
+			strings.SplitN(entry.LastError, "\n", 2)[0],
+		)
+	}
+	writer.Flush()
+	return 0
+}
+
+func (app Yacu) clearQuarantineCommand(ctx context.Context, name string) int {
+	found, err := app.ClearQuarantine(ctx, name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !found {
+		fmt.Printf("Container %s is not quarantined\n", name)
+		return 1
+	}
+
+	fmt.Printf("Quarantine of %s cleared, it is updated again on the next run\n", name)
+	return 0
+}
+
+func (app Yacu) outboxCommand() int {
+	entries, err := app.DB.GetOutbox()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fetching queued notifications failed: %v\n", err)
+		return 1
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No queued notifications")
+		return 0
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tWEBHOOK\tEVENT\tSTATUS\tATTEMPTS\tNEXT ATTEMPT\tCREATED\tLAST ERROR")
+	for _, entry := range entries {
+		next := "-"
+		if entry.Status == database.OUTBOX_QUEUED {
+			next = entry.NextAttempt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			entry.RowId,
+			entry.Webhook,
+			entry.Kind,
+			entry.Status,
+			entry.Attempts,
+			next,
+			entry.Created.Local().Format(time.DateTime),
+			strings.SplitN(entry.LastError, "\n", 2)[0],
+		)
+	}
+	writer.Flush()
+	return 0
+}
+
+func (app Yacu) retryOutboxCommand(id string) int {
+	var rowid int64
+	if len(id) > 0 {
+		var err error
+		if rowid, err = strconv.ParseInt(id, 10, 64); err != nil || rowid <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid notification id %s\n", id)
+			return 2
+		}
+	}
+
+	count, err := app.DB.RetryOutbox(rowid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "queueing notifications failed: %v\n", err)
+		return 1
+	}
+	if count == 0 {
+		fmt.Println("No failed notifications found")
+		return 1
+	}
+
+	fmt.Printf("Queued %d notification(s) again\n", count)
+	return 0
+}
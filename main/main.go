@@ -4,15 +4,23 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/adhocore/gronx"
 	"github.com/docker/docker/client"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/terrails/yacu/types/config"
+	"github.com/terrails/yacu/types/lock"
+	"github.com/terrails/yacu/types/mqtt"
 	"github.com/terrails/yacu/types/webhook"
 	webhooks "github.com/terrails/yacu/types/webhook/impl"
 	"github.com/terrails/yacu/utils"
+
+	yacuregistry "github.com/terrails/yacu/types/registry"
 )
 
 func main() {
@@ -45,40 +53,56 @@ func main() {
 
 	yacu := Yacu{
 		Client:     client,
-		Webhooks:   webhook.NewWebhookHandler(),
+		Webhooks:   webhook.NewWebhookHandler(*database),
 		DB:         *database,
 		Scanner:    config.Scanner,
 		Updater:    config.Updater,
+		Backup:     config.Backup,
+		Approval:   config.Approval,
+		Retry:      config.Retry,
 		Registries: config.Registries,
+		runLock:    lock.NewFileLock(config.Database.LockPath()),
+		breaker:    yacuregistry.NewBreaker(config.Retry.CircuitBreaker),
+	}
+
+	setupWebhooks(ctx, yacu.Webhooks, config.Webhooks, config.Notify)
+	yacu.Webhooks.SetDeduplication(config.Notifications)
+	yacu.Webhooks.SetRetries(config.Notifications.Retries)
+
+	if flag.NArg() > 0 {
+		setupRoutes(ctx, yacu.Webhooks, config.Routes)
+		os.Exit(yacu.RunCommand(ctx, flag.Args()))
 	}
 
-	if val, ok := config.Webhooks["discord"]; ok {
-		if len(val.Url) > 0 {
-			discordHook, err := webhooks.SetupDiscordWebhook(ctx, &val)
-			if err != nil {
-				logger.Err(err).Msg("setting up discord webhook client failed")
-			} else {
-				defVal := true
-				if val.Kind.Errors == nil {
-					val.Kind.Errors = &defVal
-				}
-				if val.Kind.ImageSuccess == nil {
-					val.Kind.ImageSuccess = &defVal
-				}
-				if val.Kind.ContainerSuccess == nil {
-					val.Kind.ContainerSuccess = &defVal
-				}
-
-				yacu.Webhooks.Append(discordHook, &val.Kind)
-				logger.Debug().Msg("discord webhook client initialized")
-			}
+	if len(config.Mqtt.Broker) > 0 {
+		setupMqtt(ctx, &yacu, config.Mqtt)
+	}
+	// routes are validated once every notifier is registered
+	setupRoutes(ctx, yacu.Webhooks, config.Routes)
+	go handleShutdown(ctx, &yacu)
+
+	if len(config.Api.Listen) > 0 {
+		if !config.Api.IsAuthValid() {
+			logger.Fatal().Str("listen", config.Api.Listen).Msg("api token is required, set insecure to serve the api without one")
 		}
+		go yacu.ServeApi(ctx, config.Api)
 	}
 
 	if !config.Scanner.IsIntervalValid() {
 		logger.Fatal().Str("interval", config.Scanner.Interval).Msg("invalid cron format")
 	}
 
+	if !config.Scanner.IsCheckIntervalValid() {
+		logger.Fatal().Int("min_check_interval", config.Scanner.MinCheckInterval).Int("max_check_interval", config.Scanner.MaxCheckInterval).Msg("invalid check interval bounds")
+	}
+
+	if !config.Scanner.IsAgeSourceValid() {
+		logger.Fatal().Str("age_source", config.Scanner.AgeSource).Msg("invalid image age source")
+	}
+
+	yacu.Webhooks.RunDigests(ctx)
+	yacu.Webhooks.RunOutbox(ctx)
+
 	logger.Info().Msg("initialization completed")
 
 	for {
@@ -100,3 +124,138 @@ func main() {
 		yacu.Run(ctx)
 	}
 }
+
+func setupWebhooks(ctx context.Context, handler *webhook.Webhooks, hooks config.Webhooks, notify config.Notify) {
+	logger := zerolog.Ctx(ctx)
+
+	for name, val := range hooks {
+		if len(val.Url) == 0 && len(val.Token) == 0 && len(val.Host) == 0 {
+			continue
+		}
+		setupWebhook(ctx, handler, name, val)
+	}
+
+	// urls are named after their service unless set otherwise
+	names := map[string]bool{}
+	for name := range hooks {
+		names[name] = true
+	}
+	for i, raw := range notify {
+		name, val, err := config.ParseNotifyUrl(raw)
+		if err != nil {
+			logger.Err(err).Int("index", i).Msg("invalid notify url")
+			continue
+		}
+		if names[name] {
+			logger.Error().Str("webhook", name).Msg("webhook name is already used, set another one with the name parameter")
+			continue
+		}
+		names[name] = true
+		setupWebhook(ctx, handler, name, *val)
+	}
+}
+
+func setupRoutes(ctx context.Context, handler *webhook.Webhooks, routes config.Routes) {
+	if err := handler.SetRoutes(ctx, routes); err != nil {
+		zerolog.Ctx(ctx).Fatal().Err(err).Msg("invalid notification routes")
+	}
+}
+
+func setupWebhook(ctx context.Context, handler *webhook.Webhooks, name string, val config.Webhook) {
+	service := val.Type
+	if len(service) == 0 {
+		service = name
+	}
+	logger := zerolog.Ctx(ctx).With().Str("webhook", name).Str("service", service).Logger()
+
+	if !val.IsModeValid() {
+		logger.Error().Str("mode", string(val.Mode)).Msg("unknown webhook mode")
+		return
+	}
+
+	if val.Mode == config.WEBHOOK_MODE_DIGEST && !val.IsDigestValid() {
+		logger.Error().Str("digest", val.Digest).Msg("invalid digest schedule")
+		return
+	}
+
+	var hook webhook.Notifier
+	var err error
+	switch service {
+	case "discord":
+		hook, err = webhooks.SetupDiscordWebhook(ctx, &val)
+	case "slack":
+		hook, err = webhooks.SetupSlackWebhook(ctx, &val)
+	case "mattermost":
+		hook, err = webhooks.SetupMattermostWebhook(ctx, &val)
+	case "telegram":
+		hook, err = webhooks.SetupTelegramWebhook(ctx, &val)
+	case "ntfy":
+		hook, err = webhooks.SetupNtfyWebhook(ctx, &val)
+	case "gotify":
+		hook, err = webhooks.SetupGotifyWebhook(ctx, &val)
+	case "smtp":
+		hook, err = webhooks.SetupSmtpWebhook(ctx, &val)
+	case "matrix":
+		hook, err = webhooks.SetupMatrixWebhook(ctx, &val)
+	case "generic":
+		hook, err = webhooks.SetupGenericWebhook(ctx, &val)
+	default:
+		logger.Warn().Msg("unknown webhook type")
+		return
+	}
+	if err != nil {
+		logger.Err(err).Msg("setting up webhook client failed")
+		return
+	}
+
+	defVal := true
+	if val.Kind.Errors == nil {
+		val.Kind.Errors = &defVal
+	}
+	if val.Kind.ImageSuccess == nil {
+		val.Kind.ImageSuccess = &defVal
+	}
+	if val.Kind.ContainerSuccess == nil {
+		val.Kind.ContainerSuccess = &defVal
+	}
+	if val.Kind.Pending == nil {
+		val.Kind.Pending = &defVal
+	}
+
+	handler.Append(name, hook, &val)
+	logger.Debug().Msg("webhook client initialized")
+}
+
+// disconnects from the MQTT broker on shutdown so home assistant marks the entities unavailable right away
+func handleShutdown(ctx context.Context, yacu *Yacu) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+
+	zerolog.Ctx(ctx).Info().Msg("shutting down")
+	if yacu.Mqtt != nil {
+		yacu.Mqtt.Disconnect()
+	}
+	os.Exit(0)
+}
+
+func setupMqtt(ctx context.Context, yacu *Yacu, mqttConfig config.Mqtt) {
+	logger := zerolog.Ctx(ctx)
+
+	client, err := mqtt.Connect(ctx, mqttConfig, func(name string) {
+		yacu.InstallUpdate(ctx, name)
+	})
+	if err != nil {
+		logger.Err(err).Msg("connecting to MQTT broker failed")
+		return
+	}
+	yacu.Mqtt = client
+
+	// errors and run results are published to their own topics
+	enabled := true
+	yacu.Webhooks.Append("mqtt", client, &config.Webhook{
+		Mode: config.WEBHOOK_MODE_BOTH,
+		Kind: config.WebhookKind{ImageSuccess: &enabled, ContainerSuccess: &enabled, Errors: &enabled, Pending: &enabled},
+	})
+	logger.Debug().Msg("MQTT client initialized")
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/image"
+	"github.com/terrails/yacu/types/mqtt"
+	"github.com/terrails/yacu/types/summary"
+	"github.com/terrails/yacu/utils"
+
+	yacucontainer "github.com/terrails/yacu/types/container"
+)
+
+// publishes the update state of scanned containers if mqtt is enabled
+func (app Yacu) PublishStates(ctx context.Context, containers yacucontainer.Containers) {
+	if app.Mqtt == nil {
+		return
+	}
+
+	states := []mqtt.ContainerState{}
+	for _, container := range containers {
+		states = append(states, app.containerState(ctx, container))
+	}
+	app.Mqtt.PublishContainers(ctx, states)
+}
+
+// publishes the state of containers updated during the run, their scanned state is outdated
+func (app Yacu) PublishUpdated(ctx context.Context, run *summary.Summary) {
+	if app.Mqtt == nil {
+		return
+	}
+	logger := zerolog.Ctx(ctx)
+
+	for _, item := range run.Updated {
+		container, err := app.inspectContainer(item.Name)
+		if err != nil {
+			logger.Err(err).Str("container", item.Name).Msg("Inspecting updated container failed")
+			continue
+		}
+		if err := app.Mqtt.PublishContainer(app.containerState(ctx, container)); err != nil {
+			logger.Err(err).Str("container", item.Name).Msg("Publishing container state to MQTT failed")
+		}
+	}
+}
+
+func (app Yacu) containerState(ctx context.Context, container *yacucontainer.Container) mqtt.ContainerState {
+	logger := zerolog.Ctx(ctx)
+
+	installed := version(container.Image.Metadata, container.Image.RepoDigest.String())
+	state := mqtt.ContainerState{
+		Name:             container.CleanName(),
+		Title:            container.RepositoryFamiliarized(),
+		InstalledVersion: installed,
+		LatestVersion:    installed,
+		ReleaseUrl:       container.Image.Metadata.SourceUrl(),
+	}
+	if len(state.ReleaseUrl) == 0 {
+		state.ReleaseUrl = utils.RegistryPageUrl(container.Repository)
+	}
+
+	latest := container.RemoteDigest
+	if len(latest) == 0 {
+		remote, err := app.DB.GetRemoteImage(container.Repository.String(), container.Image.Platform())
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				logger.Err(err).Str("container", container.Name).Msg("Fetching remote image data from local database failed")
+			}
+			return state
+		}
+		latest = remote.Digest
+	}
+
+	if !container.HasRepoDigest(latest) {
+		state.LatestVersion = utils.ShortId(latest.String())
+		state.ReleaseSummary = fmt.Sprintf("New image of %s found, digest %s → %s",
+			container.RepositoryFamiliarized(), utils.ShortId(container.Image.RepoDigest.String()), utils.ShortId(latest.String()),
+		)
+	}
+	return state
+}
+
+// version label with the digest, as different images may share a version
+func version(metadata image.Metadata, digest string) string {
+	if len(digest) == 0 {
+		return metadata.Version
+	}
+	if len(metadata.Version) == 0 {
+		return utils.ShortId(digest)
+	}
+	return fmt.Sprintf("%s (%s)", metadata.Version, utils.ShortId(digest))
+}
+
+// updates a single container, requested through home assistant. The same checks as in a scheduled run apply,
+// so containers that are not scanned, need an approval or whose image is not old enough are not updated
+func (app Yacu) InstallUpdate(ctx context.Context, name string) {
+	logger := zerolog.Ctx(ctx).With().Str("container", name).Logger()
+	ctx = logger.WithContext(ctx)
+
+	if err := app.runLock.Lock(); err != nil {
+		logger.Err(err).Msg("Acquiring run lock failed")
+		return
+	}
+	defer app.runLock.Unlock()
+
+	container, err := app.inspectContainer(name)
+	if err != nil {
+		logger.Err(err).Msg("Inspecting container for install failed")
+		app.Webhooks.Error(ctx, fmt.Sprintf("Unable to install update of %s", name), err)
+		return
+	}
+
+	if !container.ShouldScan(app.Scanner.ScanAll, app.Scanner.ScanStopped) {
+		logger.Warn().Msg("Install requested for a container that is not scanned, ignoring")
+		return
+	}
+
+	run := summary.New()
+	defer app.FinishRun(ctx, run)
+
+	if yes, err := app.CheckForUpdate(ctx, container, run); err != nil {
+		logger.Err(err).Msg("Checking for update failed")
+		app.Webhooks.ImageError(ctx, container.Image, fmt.Sprintf("Unable to install update of %s", name), err)
+		run.AddFailed(summary.STAGE_CHECK, container.CleanName(), container.RepositoryFamiliarized(), "Unable to check for updates", err)
+		return
+	} else if !yes {
+		if container.RequiresApproval(app.Approval.Enabled) {
+			logger.Info().Msg("No approved update to install")
+		} else {
+			logger.Info().Msg("No update to install")
+		}
+		return
+	}
+
+	app.ApplyUpdates(ctx, yacucontainer.Containers{container}, run)
+}
+
+func (app Yacu) inspectContainer(name string) (*yacucontainer.Container, error) {
+	data, err := app.Client.ContainerInspect(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	return yacucontainer.New(app.Client, &data, app.Updater.StopTimeout, app.Scanner.ImageAge)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/database"
+	"github.com/terrails/yacu/types/summary"
+
+	yacucontainer "github.com/terrails/yacu/types/container"
+)
+
+// counts consecutive failed updates to the same digest and quarantines the container once there are too many,
+// the run that quarantines it records the skip of its next updates
+func (app Yacu) RecordUpdateFailure(ctx context.Context, container *yacucontainer.Container, updateErr error, run *summary.Summary) {
+	logger := zerolog.Ctx(ctx).With().Str("container", container.Name).Logger()
+
+	failure, err := app.DB.GetUpdateFailure(container.CleanName())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logger.Err(err).Msg("Fetching update failures from local database failed")
+		return
+	}
+
+	now := time.Now()
+	// a new digest gets a fresh start
+	if failure == nil || failure.Digest != container.RemoteDigest {
+		failure = &database.UpdateFailureRow{
+			Container:   container.CleanName(),
+			Digest:      container.RemoteDigest,
+			FirstFailed: now,
+		}
+	}
+	failure.Image = container.RepositoryFamiliarized()
+	failure.Count += 1
+	failure.LastError = updateErr.Error()
+	failure.LastFailed = now
+
+	quarantine := app.Updater.QuarantineAfter > 0 && failure.Count >= app.Updater.QuarantineAfter && !failure.Quarantined
+	if quarantine {
+		failure.Quarantined = true
+	}
+
+	if err := app.DB.SaveUpdateFailure(failure); err != nil {
+		logger.Err(err).Msg("Writing update failures to local database failed")
+		return
+	}
+
+	if quarantine {
+		logger.Warn().Int("failures", failure.Count).Str("digest", failure.Digest.String()).Msg("Container quarantined")
+		run.AddSkipped(container.CleanName(), container.RepositoryFamiliarized(), fmt.Sprintf("quarantined after %d failed updates", failure.Count))
+		app.Webhooks.ContainerQuarantined(ctx, container, failure)
+	}
+}
+
+// leaves out containers quarantined for the digest they would be updated to, a newer digest lifts the quarantine
+func (app Yacu) WithoutQuarantined(ctx context.Context, containers yacucontainer.Containers) yacucontainer.Containers {
+	logger := zerolog.Ctx(ctx)
+
+	remaining := yacucontainer.Containers{}
+	for _, container := range containers {
+		failure, err := app.DB.GetUpdateFailure(container.CleanName())
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			logger.Err(err).Str("container", container.Name).Msg("Fetching update failures from local database failed")
+		}
+
+		if failure == nil || !failure.Quarantined || failure.Digest != container.RemoteDigest {
+			remaining = append(remaining, container)
+			continue
+		}
+
+		// already part of the summary of the run that quarantined it
+		logger.Info().Str("container", container.Name).Int("failures", failure.Count).Msg("Skipping quarantined container")
+		app.Webhooks.ContainerQuarantined(ctx, container, failure)
+	}
+	return remaining
+}
+
+// lifts the quarantine of the container and forgets its failed updates, returns whether it had any
+func (app Yacu) ClearQuarantine(ctx context.Context, name string) (bool, error) {
+	found, err := app.DB.DeleteUpdateFailure(name)
+	if err != nil {
+		zerolog.Ctx(ctx).Err(err).Str("container", name).Msg("Removing update failures from local database failed")
+		return false, fmt.Errorf("removing update failures of %s from local database failed: %w", name, err)
+	}
+
+	if found {
+		zerolog.Ctx(ctx).Info().Str("container", name).Msg("Quarantine cleared")
+	}
+	return found, nil
+}
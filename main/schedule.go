@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/config"
+	"github.com/terrails/yacu/types/database"
+	"github.com/terrails/yacu/utils"
+)
+
+// time of the next registry check of the image, based on how often its digest changed before
+func (app Yacu) NextCheck(ctx context.Context, image *database.RemoteImageRow) (time.Time, error) {
+	history, err := app.DB.GetDigestHistory(image.Reference)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("fetching digest history of %s from local database failed: %w", image.Reference, err)
+	}
+
+	interval := CheckInterval(app.Scanner, history, time.Now().UTC())
+	zerolog.Ctx(ctx).Debug().Str("interval", utils.HumanizeDuration(interval)).Int("digests", len(history)).Msg("Check interval calculated")
+
+	return image.LastCheck.Add(interval), nil
+}
+
+// half of the typical time between digest changes, so a release is noticed within half a cycle
+func CheckInterval(scanner config.Scanner, history []*database.ImageDigestRow, now time.Time) time.Duration {
+	if len(history) == 0 {
+		return scanner.ClampCheckInterval(0)
+	}
+
+	gaps := []time.Duration{}
+	for i := 1; i < len(history); i++ {
+		gaps = append(gaps, history[i].FirstSeen.Sub(history[i-1].FirstSeen))
+	}
+
+	// time since the current digest appeared is a lower bound of the ongoing cycle,
+	// images that stopped changing are backed off instead of being checked at their old cadence
+	cadence := now.Sub(history[len(history)-1].FirstSeen)
+	if len(gaps) > 0 {
+		slices.Sort(gaps)
+		cadence = max(cadence, gaps[len(gaps)/2])
+	}
+
+	return scanner.ClampCheckInterval(cadence / 2)
+}
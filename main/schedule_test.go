@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/terrails/yacu/types/config"
+	"github.com/terrails/yacu/types/database"
+)
+
+func TestCheckInterval(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	scanner := config.Scanner{MinCheckInterval: 6, MaxCheckInterval: 720}
+
+	// digests first seen the given amount of hours before now, oldest first
+	history := func(hours ...int) []*database.ImageDigestRow {
+		rows := []*database.ImageDigestRow{}
+		for _, h := range hours {
+			rows = append(rows, &database.ImageDigestRow{FirstSeen: now.Add(-time.Duration(h) * time.Hour)})
+		}
+		return rows
+	}
+
+	tests := []struct {
+		name    string
+		scanner config.Scanner
+		history []*database.ImageDigestRow
+		want    time.Duration
+	}{
+		{
+			name:    "no history uses the lower bound",
+			scanner: scanner,
+			history: nil,
+			want:    6 * time.Hour,
+		},
+		{
+			name:    "new single digest uses the lower bound",
+			scanner: scanner,
+			history: history(2),
+			want:    6 * time.Hour,
+		},
+		{
+			name:    "single digest backs off with its age",
+			scanner: scanner,
+			history: history(100),
+			want:    50 * time.Hour,
+		},
+		{
+			name:    "daily releases",
+			scanner: scanner,
+			history: history(96, 72, 48, 24, 1),
+			want:    12 * time.Hour,
+		},
+		{
+			name:    "median ignores a single outlier",
+			scanner: scanner,
+			history: history(500, 476, 452, 428, 28, 4),
+			want:    12 * time.Hour,
+		},
+		{
+			name:    "overdue release backs off",
+			scanner: scanner,
+			history: history(148, 124, 100),
+			want:    50 * time.Hour,
+		},
+		{
+			name:    "rare releases are limited by the upper bound",
+			scanner: scanner,
+			history: history(8000, 4000, 2000),
+			want:    720 * time.Hour,
+		},
+		{
+			name:    "frequent releases are limited by the lower bound",
+			scanner: scanner,
+			history: history(4, 3, 2, 1),
+			want:    6 * time.Hour,
+		},
+		{
+			name:    "zero bounds check every run",
+			scanner: config.Scanner{MinCheckInterval: 0, MaxCheckInterval: 0},
+			history: history(8000, 4000),
+			want:    0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := CheckInterval(test.scanner, test.history, now); got != test.want {
+				t.Errorf("CheckInterval() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
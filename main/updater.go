@@ -16,11 +16,17 @@ import (
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/client"
+	"github.com/opencontainers/go-digest"
 	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/backup"
 	"github.com/terrails/yacu/types/config"
 	"github.com/terrails/yacu/types/database"
 	"github.com/terrails/yacu/types/image"
+	"github.com/terrails/yacu/types/lock"
+	"github.com/terrails/yacu/types/mqtt"
+	"github.com/terrails/yacu/types/retry"
 	"github.com/terrails/yacu/types/set"
+	"github.com/terrails/yacu/types/summary"
 	"github.com/terrails/yacu/types/webhook"
 	"github.com/terrails/yacu/utils"
 	"golang.org/x/exp/maps"
@@ -37,16 +43,42 @@ type Yacu struct {
 	DB         database.Database
 	Scanner    config.Scanner
 	Updater    config.Updater
+	Backup     config.Backup
+	Approval   config.Approval
+	Retry      config.Retry
 	Registries config.RegistryEntries
+
+	// publishes container states to home assistant, nil if disabled
+	Mqtt *mqtt.Client
+
+	// prevents scheduled runs and approved updates from recreating containers at the same time,
+	// also when they are started by another process such as the approve command
+	runLock *lock.FileLock
+	// skips registries that keep failing for the rest of a run
+	breaker *yacuregistry.Breaker
 }
 
-func (app Yacu) Run(ctx context.Context) {
+// scans and updates all containers, a failing container or image does not stop the others
+func (app Yacu) Run(ctx context.Context) *summary.Summary {
 	logger := zerolog.Ctx(ctx)
 
-	containers, err := app.FetchUpdates(ctx)
+	run := summary.New()
+	if err := app.runLock.Lock(); err != nil {
+		logger.Err(err).Msg("Acquiring run lock failed")
+		run.AddFailed(summary.STAGE_SCAN, "scanner", "", "Unable to start run", err)
+		return run
+	}
+	defer app.runLock.Unlock()
+	defer app.FinishRun(ctx, run)
+
+	// every run gives unreachable registries another chance
+	app.breaker.Reset()
+
+	containers, err := app.FetchUpdates(ctx, run)
 	if err != nil {
 		app.Webhooks.Error(ctx, "Unable to fetch updates", err)
-		return
+		run.AddFailed(summary.STAGE_SCAN, "scanner", "", "Unable to fetch updates", err)
+		return run
 	}
 
 	if len(containers) == 0 {
@@ -55,200 +87,446 @@ func (app Yacu) Run(ctx context.Context) {
 		logger.Info().Int("count", len(containers)).Msg("Found new updates")
 	}
 
-	// pull all new images at once
-	for _, container := range containers {
-		imageLogger := logger.With().Str("service", "image_pull").Str("image", container.RepositoryFamiliarized()).Logger()
-		imageCtx := imageLogger.WithContext(context.Background())
-
-		// check if image has already been pulled in case that multiple containers with the same image are being updated
-		if yes, err := app.IsLatestImagePresent(imageCtx, container.Repository); err != nil {
-			app.Webhooks.ImageError(imageCtx, container.Image, "Unable to check if image is latest", err)
-			return
-		} else if !yes {
-			imageLogger.Debug().Msg("Pulling image")
-
-			if err = app.PullImage(imageCtx, container.Repository); err != nil {
-				app.Webhooks.ImageError(imageCtx, container.Image, "Unable to pull image", err)
-				return
-			}
+	app.ApplyUpdates(ctx, containers, run)
 
-			newImageRaw, _, err := app.Client.ImageInspectWithRaw(context.Background(), container.Repository.String())
-			if err != nil {
-				imageLogger.Err(err).Msg("ImageInspect request failed")
-				app.Webhooks.ImageError(imageCtx, container.Image, "Unable to inspect image", err)
-				return
-			}
+	// only a full scan tells whether earlier errors stopped occurring
+	app.Webhooks.Resolve(ctx, run.Started, run.Succeeded())
+	return run
+}
 
-			newImageData, err := image.NewData(&newImageRaw, container.Repository)
-			if err != nil {
-				app.Webhooks.ImageError(imageCtx, container.Image, "Unable to initialize image", err)
-				return
-			}
+// applies approved updates without waiting for the next scheduled run
+func (app Yacu) RunApproved(ctx context.Context) *summary.Summary {
+	logger := zerolog.Ctx(ctx)
 
-			imageLogger.Info().Msg("Pulled image")
-			app.Webhooks.ImageUpdated(imageCtx, container.Image, newImageData)
-		}
+	run := summary.New()
+	if err := app.runLock.Lock(); err != nil {
+		logger.Err(err).Msg("Acquiring run lock failed")
+		run.AddFailed(summary.STAGE_SCAN, "scanner", "", "Unable to start run", err)
+		return run
 	}
+	defer app.runLock.Unlock()
+	defer app.FinishRun(ctx, run)
 
-	successCount := 0
-	imgToRemove := set.NewImageSet()
+	containers, err := app.FetchApprovedUpdates(ctx, run)
+	if err != nil {
+		app.Webhooks.Error(ctx, "Unable to fetch approved updates", err)
+		run.AddFailed(summary.STAGE_SCAN, "scanner", "", "Unable to fetch approved updates", err)
+		return run
+	}
+
+	if len(containers) == 0 {
+		logger.Info().Msg("No approved updates found")
+		return run
+	}
 
-	// update all containers
+	logger.Info().Int("count", len(containers)).Msg("Applying approved updates")
+	app.ApplyUpdates(ctx, containers, run)
+	return run
+}
+
+// completes the run summary and sends it to webhooks that want one
+func (app Yacu) FinishRun(ctx context.Context, run *summary.Summary) {
+	logger := zerolog.Ctx(ctx)
+
+	run.Finish()
+	app.PublishUpdated(ctx, run)
+
+	logger.Info().
+		Int("updated", len(run.Updated)).
+		Int("failed", len(run.Failed)).
+		Int("skipped", len(run.Skipped)).
+		Int("held", len(run.Held)).
+		Interface("failed_stages", run.FailedStages()).
+		Dur("duration", run.Duration()).
+		Msg("Run completed")
+
+	if run.HasChanges() {
+		app.Webhooks.RunSummary(ctx, run)
+	}
+}
+
+func (app Yacu) ApplyUpdates(ctx context.Context, containers yacucontainer.Containers, run *summary.Summary) {
+	logger := zerolog.Ctx(ctx)
+
+	containers = app.WithoutQuarantined(ctx, containers)
+
+	// pull all new images at once, containers whose image could not be pulled are left out
+	pulls := map[string]error{}
+	pulled := yacucontainer.Containers{}
 	for _, container := range containers {
-		containerLogger := logger.With().Str("service", "container_update").Str("container", container.Name).Str("image", container.RepositoryFamiliarized()).Logger()
-		containerCtx := containerLogger.WithContext(context.Background())
+		key := container.RepositoryFamiliarized() + "@" + container.RemoteDigest.String()
+		if _, ok := pulls[key]; !ok {
+			pulls[key] = app.PullUpdate(ctx, container, run)
+		}
 
-		containerLogger.Debug().Msg("Updating container")
+		if pulls[key] != nil {
+			run.AddSkipped(container.CleanName(), container.RepositoryFamiliarized(), "new image could not be pulled")
+			continue
+		}
+		pulled = append(pulled, container)
+	}
+	containers = pulled
 
-		var updateWarnings []string = []string{}
+	successCount := 0
+	imgToRemove := set.NewImageSet()
 
-		shouldRestart := container.IsRunning()
-		var dependantContainers yacucontainer.DependantContainers = nil
+	// update all containers, containers sharing an image are rolled out together
+	for _, group := range containers.GroupByImage() {
+		if app.Updater.Canary.Enabled && len(group) > 1 {
+			canary := group.Canary()
+			group = group.Without(canary)
 
-		if shouldRestart {
-			dependantContainers, err := app.GetDependingContainers(containerCtx, container.Raw)
-			if err != nil {
-				app.Webhooks.ContainerError(containerCtx, container, "Unable to fetch depending containers", err)
+			if err := app.UpdateCanary(ctx, canary, group, run); err != nil {
+				for _, container := range group {
+					run.AddSkipped(container.CleanName(), container.RepositoryFamiliarized(), fmt.Sprintf("canary %s failed", canary.CleanName()))
+				}
 				continue
 			}
 
-			updateWarnings = append(updateWarnings, dependantContainers.Stop(containerCtx, app.Client)...)
-			if len(updateWarnings) > 0 {
-				containerLogger.Warn().Str("warnings", fmt.Sprintf("%v", updateWarnings)).Msg("Received warnings while stopping depending containers")
-			}
+			successCount += 1
+			imgToRemove.Add(canary.Image)
+		}
 
-			if err = container.Stop(containerCtx, app.Client); err != nil {
-				app.Webhooks.ContainerError(containerCtx, container, "Unable to stop container", err)
+		for _, container := range group {
+			if _, err := app.UpdateContainer(ctx, container, run); err != nil {
 				continue
 			}
-		}
 
-		var singleNetSettings network.NetworkingConfig = network.NetworkingConfig{}
-		for netName, netSettings := range container.Raw.NetworkSettings.Networks {
-			singleNetSettings.EndpointsConfig = map[string]*network.EndpointSettings{
-				netName: netSettings,
-			}
-			break
+			successCount += 1
+			imgToRemove.Add(container.Image)
 		}
+	}
 
-		containerLogger.Debug().Msg("Removing container")
-		if err := app.Client.ContainerRemove(
-			context.Background(),
-			container.ID,
-			types.ContainerRemoveOptions{
-				Force:         true,
-				RemoveVolumes: app.Updater.RemoveVolumes,
-			},
-		); err != nil {
-			containerLogger.Err(err).Msg("Failed to remove container")
-			app.Webhooks.ContainerError(containerCtx, container, "Unable to remove container", err)
-			continue
+	logger.Info().Int("total", len(containers)).Int("successful", successCount).Msg("Container updates completed")
+
+	if app.Updater.RemoveImages && len(imgToRemove.Items) > 0 {
+		logger.Debug().Int("count", len(imgToRemove.Items)).Msg("Removing unused images")
+		count := app.RemoveUnusedImages(ctx, run, maps.Values(imgToRemove.Items)...)
+		logger.Info().Int("count", count).Msg("Removed unused images")
+	}
+}
+
+// pulls the image the container is updated to unless present, errors are sent to webhooks and added to the run
+func (app Yacu) PullUpdate(ctx context.Context, container *yacucontainer.Container, run *summary.Summary) error {
+	imageLogger := zerolog.Ctx(ctx).With().Str("service", "image_pull").Str("image", container.RepositoryFamiliarized()).Logger()
+	imageCtx := imageLogger.WithContext(context.Background())
+
+	failed := func(context string, err error) error {
+		app.Webhooks.ImageError(imageCtx, container.Image, context, err)
+		run.AddFailed(summary.STAGE_PULL, container.RepositoryFamiliarized(), container.RepositoryFamiliarized(), context, err)
+		return err
+	}
+
+	// check if image has already been pulled in case that multiple containers with the same image are being updated
+	if yes, err := app.IsLatestImagePresent(imageCtx, container.Repository, container.RemoteDigest); err != nil {
+		return failed("Unable to check if image is latest", err)
+	} else if yes {
+		return nil
+	}
+
+	imageLogger.Debug().Msg("Pulling image")
+
+	if err := app.PullImage(imageCtx, container.Repository, container.RemoteDigest); err != nil {
+		return failed("Unable to pull image", err)
+	}
+
+	newImageRaw, err := retry.Value(imageCtx, app.Retry.Docker, func() (types.ImageInspect, error) {
+		data, _, err := app.Client.ImageInspectWithRaw(context.Background(), container.Repository.String())
+		return data, err
+	})
+	if err != nil {
+		imageLogger.Err(err).Msg("ImageInspect request failed")
+		return failed("Unable to inspect image", err)
+	}
+
+	newImageData, err := image.NewData(&newImageRaw, container.Repository)
+	if err != nil {
+		return failed("Unable to initialize image", err)
+	}
+
+	imageLogger.Info().Msg("Pulled image")
+	run.AddPulled(container.RepositoryFamiliarized())
+	app.Webhooks.ImageUpdated(imageCtx, container.Image, newImageData)
+	return nil
+}
+
+// recreates the container using the already pulled image, success and errors are sent to webhooks and stored in history
+func (app Yacu) UpdateContainer(ctx context.Context, container *yacucontainer.Container, run *summary.Summary) (*yacucontainer.Container, error) {
+	history := &database.UpdateHistoryRow{
+		Container:  container.CleanName(),
+		Image:      container.RepositoryFamiliarized(),
+		PrevDigest: container.Image.RepoDigest.String(),
+		NewDigest:  container.RemoteDigest.String(),
+		Hooks:      []database.HookOutput{},
+		Started:    time.Now(),
+	}
+
+	newContainer, warnings, err := app.recreateContainer(ctx, container, history)
+
+	history.Finished = time.Now()
+	history.Status = database.HISTORY_SUCCESS
+	if err != nil {
+		history.Status = database.HISTORY_FAILED
+		history.Message = err.Error()
+		run.AddFailed(summary.STAGE_UPDATE, container.CleanName(), container.RepositoryFamiliarized(), "Update failed", err)
+		app.RecordUpdateFailure(ctx, container, err, run)
+	} else {
+		run.AddUpdated(container.CleanName(), container.RepositoryFamiliarized(), history.Finished.Sub(history.Started), warnings...)
+		if _, err := app.DB.DeleteUpdateFailure(container.CleanName()); err != nil {
+			zerolog.Ctx(ctx).Err(err).Str("container", container.Name).Msg("Removing update failures from local database failed")
 		}
+	}
 
-		containerLogger.Debug().Msg("Creating container")
-		response, err := app.Client.ContainerCreate(
-			context.Background(),
-			container.Raw.Config,
-			container.Raw.HostConfig,
-			&singleNetSettings,
-			nil,
-			container.Raw.Name,
-		)
+	if _, err := app.DB.SaveUpdateHistory(history); err != nil {
+		zerolog.Ctx(ctx).Err(err).Str("container", container.Name).Msg("Writing update history to local database failed")
+	}
+
+	return newContainer, err
+}
+
+func (app Yacu) recreateContainer(ctx context.Context, container *yacucontainer.Container, history *database.UpdateHistoryRow) (*yacucontainer.Container, []string, error) {
+	logger := zerolog.Ctx(ctx)
+	containerLogger := logger.With().Str("service", "container_update").Str("container", container.Name).Str("image", container.RepositoryFamiliarized()).Logger()
+	containerCtx := containerLogger.WithContext(context.Background())
+
+	containerLogger.Debug().Msg("Updating container")
+
+	var updateWarnings []string = []string{}
+	hooks := []*yacucontainer.HookResult{}
+	hookTimeout := time.Duration(app.Updater.HookTimeout) * time.Second
+
+	shouldRestart := container.IsRunning()
 
+	// hooks are executed inside the container so it has to be running
+	if shouldRestart {
+		hook, err := container.RunHook(containerCtx, app.Client, yacucontainer.HOOK_PRE_UPDATE, hookTimeout)
+		if hook != nil {
+			hooks = append(hooks, hook)
+			history.Hooks = append(history.Hooks, hook.HookOutput())
+		}
 		if err != nil {
-			containerLogger.Err(err).Msg("Failed to create container")
-			app.Webhooks.ContainerError(containerCtx, container, "Unable to create container", err)
-			continue
+			app.Webhooks.ContainerError(containerCtx, container, "Pre-update hook failed, update aborted", err)
+			return nil, nil, err
 		}
+	}
+	var dependantContainers yacucontainer.DependantContainers = nil
 
-		newId := response.ID
-		if len(response.Warnings) > 0 {
-			updateWarnings = append(updateWarnings, response.Warnings...)
-			containerLogger.Warn().Str("warnings", fmt.Sprintf("%v", response.Warnings)).Msg("Received warnings while creating container")
+	if shouldRestart {
+		dependants, err := app.GetDependingContainers(containerCtx, container.Raw)
+		if err != nil {
+			app.Webhooks.ContainerError(containerCtx, container, "Unable to fetch depending containers", err)
+			return nil, nil, err
 		}
+		dependantContainers = dependants
 
-		// cannot use multiple networks if host networking is enabled
-		if !container.Raw.HostConfig.NetworkMode.IsHost() {
-
-			// should be already connected to 1 network
-			if len(container.Raw.NetworkSettings.Networks) > 1 {
-				containerLogger.Debug().Msg("Connecting container to networks")
-			}
+		updateWarnings = append(updateWarnings, dependantContainers.Stop(containerCtx, app.Client)...)
+		if len(updateWarnings) > 0 {
+			containerLogger.Warn().Str("warnings", fmt.Sprintf("%v", updateWarnings)).Msg("Received warnings while stopping depending containers")
+		}
 
-			// Add other networks
-			for netName, netSettings := range container.Raw.NetworkSettings.Networks {
+		if err = container.Stop(containerCtx, app.Client); err != nil {
+			app.Webhooks.ContainerError(containerCtx, container, "Unable to stop container", err)
+			return nil, nil, err
+		}
+	}
 
-				// skip already connected
-				if _, ok := singleNetSettings.EndpointsConfig[netName]; ok {
-					continue
-				}
+	// volumes are archived while the container is stopped so that the backup is consistent
+	if container.ShouldBackup(app.Backup.Enabled) {
+		volumeBackup, err := backup.Create(containerCtx, app.Client, app.Backup, container.Raw)
+		if err != nil {
+			containerLogger.Err(err).Msg("Backing up volumes failed")
 
-				containerLogger.Debug().Str("network", netName).Msg("Connecting container to network")
-
-				if err := app.Client.NetworkConnect(
-					context.Background(),
-					netName,
-					newId,
-					netSettings,
-				); err != nil {
-					containerLogger.Err(err).Str("network", netName).Msg("Connecting to network failed")
-					// since we already came this far, might as well do everything and send a warning about it
-					updateWarnings = append(updateWarnings, fmt.Sprintf("connecting to network %s failed: %v", netName, err))
+			// bring everything back up as nothing has been changed yet
+			if shouldRestart {
+				if err := container.Start(containerCtx, app.Client); err != nil {
+					containerLogger.Err(err).Msg("Restarting container after failed backup failed")
 				}
+				dependantContainers.Start(containerCtx, app.Client)
 			}
+
+			app.Webhooks.ContainerError(containerCtx, container, "Volume backup failed, update aborted", err)
+			return nil, nil, err
 		}
 
-		newData, err := app.Client.ContainerInspect(context.Background(), newId)
-		if err != nil {
-			containerLogger.Err(err).Str("id", newId).Msg("ContainerInspect request failed")
-			app.Webhooks.ContainerError(containerCtx, container, "Unable to inspect container", err)
-			continue
+		if volumeBackup != nil {
+			history.Backup = volumeBackup.Id
+			backup.Prune(containerCtx, app.Backup, container.CleanName())
 		}
+	}
 
-		newContainer, err := yacucontainer.New(app.Client, &newData, app.Updater.StopTimeout, app.Scanner.ImageAge)
-		if err != nil {
-			containerLogger.Err(err).Str("container", newData.Name).Msg("Initializing recreated container failed")
-			app.Webhooks.ContainerError(containerCtx, container, "Unable to initialize container", err)
-			continue
+	var singleNetSettings network.NetworkingConfig = network.NetworkingConfig{}
+	for netName, netSettings := range container.Raw.NetworkSettings.Networks {
+		singleNetSettings.EndpointsConfig = map[string]*network.EndpointSettings{
+			netName: netSettings,
+		}
+		break
+	}
+
+	containerLogger.Debug().Msg("Removing container")
+	if err := app.Client.ContainerRemove(
+		context.Background(),
+		container.ID,
+		types.ContainerRemoveOptions{
+			Force:         true,
+			RemoveVolumes: app.Updater.RemoveVolumes,
+		},
+	); err != nil {
+		containerLogger.Err(err).Msg("Failed to remove container")
+		app.Webhooks.ContainerError(containerCtx, container, "Unable to remove container", err)
+		return nil, nil, err
+	}
+
+	containerLogger.Debug().Msg("Creating container")
+	response, err := app.Client.ContainerCreate(
+		context.Background(),
+		container.Raw.Config,
+		container.Raw.HostConfig,
+		&singleNetSettings,
+		nil,
+		container.Raw.Name,
+	)
+
+	if err != nil {
+		containerLogger.Err(err).Msg("Failed to create container")
+		app.Webhooks.ContainerError(containerCtx, container, "Unable to create container", err)
+		return nil, nil, err
+	}
+
+	newId := response.ID
+	if len(response.Warnings) > 0 {
+		updateWarnings = append(updateWarnings, response.Warnings...)
+		containerLogger.Warn().Str("warnings", fmt.Sprintf("%v", response.Warnings)).Msg("Received warnings while creating container")
+	}
+
+	// cannot use multiple networks if host networking is enabled
+	if !container.Raw.HostConfig.NetworkMode.IsHost() {
+
+		// should be already connected to 1 network
+		if len(container.Raw.NetworkSettings.Networks) > 1 {
+			containerLogger.Debug().Msg("Connecting container to networks")
 		}
 
-		if shouldRestart {
-			if err = newContainer.Start(containerCtx, app.Client); err != nil {
-				app.Webhooks.ContainerError(containerCtx, container, "Unable to start container", err)
+		// Add other networks
+		for netName, netSettings := range container.Raw.NetworkSettings.Networks {
+
+			// skip already connected
+			if _, ok := singleNetSettings.EndpointsConfig[netName]; ok {
 				continue
 			}
 
-			warnings := dependantContainers.Start(containerCtx, app.Client)
-			if len(warnings) > 0 {
-				updateWarnings = append(updateWarnings, warnings...)
-				containerLogger.Warn().Str("warnings", fmt.Sprintf("%v", warnings)).Msg("Received warnings while starting depending containers")
+			containerLogger.Debug().Str("network", netName).Msg("Connecting container to network")
+
+			if err := app.Client.NetworkConnect(
+				context.Background(),
+				netName,
+				newId,
+				netSettings,
+			); err != nil {
+				containerLogger.Err(err).Str("network", netName).Msg("Connecting to network failed")
+				// since we already came this far, might as well do everything and send a warning about it
+				updateWarnings = append(updateWarnings, fmt.Sprintf("connecting to network %s failed: %v", netName, err))
 			}
 		}
+	}
 
-		successCount += 1
-		imgToRemove.Add(container.Image)
-		containerLogger.Info().Msg("Updated container")
-		app.Webhooks.ContainerUpdated(containerCtx, container, newContainer, updateWarnings...)
+	newData, err := retry.Value(containerCtx, app.Retry.Docker, func() (types.ContainerJSON, error) {
+		return app.Client.ContainerInspect(context.Background(), newId)
+	})
+	if err != nil {
+		containerLogger.Err(err).Str("id", newId).Msg("ContainerInspect request failed")
+		app.Webhooks.ContainerError(containerCtx, container, "Unable to inspect container", err)
+		return nil, nil, err
 	}
 
-	logger.Info().Int("total", len(containers)).Int("successful", successCount).Msg("Container updates completed")
+	newContainer, err := yacucontainer.New(app.Client, &newData, app.Updater.StopTimeout, app.Scanner.ImageAge)
+	if err != nil {
+		containerLogger.Err(err).Str("container", newData.Name).Msg("Initializing recreated container failed")
+		app.Webhooks.ContainerError(containerCtx, container, "Unable to initialize container", err)
+		return nil, nil, err
+	}
 
-	if app.Updater.RemoveImages && len(imgToRemove.Items) > 0 {
-		logger.Debug().Int("count", len(imgToRemove.Items)).Msg("Removing unused images")
-		count := app.RemoveUnusedImages(ctx, maps.Values(imgToRemove.Items)...)
-		logger.Info().Int("count", count).Msg("Removed unused images")
+	if shouldRestart {
+		if err = newContainer.Start(containerCtx, app.Client); err != nil {
+			app.Webhooks.ContainerError(containerCtx, container, "Unable to start container", err)
+			return nil, nil, err
+		}
+
+		warnings := dependantContainers.Start(containerCtx, app.Client)
+		if len(warnings) > 0 {
+			updateWarnings = append(updateWarnings, warnings...)
+			containerLogger.Warn().Str("warnings", fmt.Sprintf("%v", warnings)).Msg("Received warnings while starting depending containers")
+		}
+
+		hook, err := newContainer.RunHook(containerCtx, app.Client, yacucontainer.HOOK_POST_UPDATE, hookTimeout)
+		if hook != nil {
+			hooks = append(hooks, hook)
+			history.Hooks = append(history.Hooks, hook.HookOutput())
+		}
+		if err != nil {
+			// container is already recreated, nothing to abort anymore
+			updateWarnings = append(updateWarnings, err.Error())
+		}
+
+		// only a container passing its probes counts as updated, one without probes should at least not crash loop
+		if probes := newContainer.Probes(time.Duration(app.Updater.VerifyTimeout) * time.Second); probes != nil {
+			if err := newContainer.Verify(containerCtx, app.Client, probes); err != nil {
+				app.Webhooks.ContainerError(containerCtx, newContainer, "Verification failed", err)
+				return nil, nil, err
+			}
+		} else if app.Updater.VerifyWatch > 0 {
+			if err := newContainer.WatchRestarts(containerCtx, app.Client, time.Duration(app.Updater.VerifyWatch)*time.Second); err != nil {
+				app.Webhooks.ContainerError(containerCtx, newContainer, "Verification failed", err)
+				return nil, nil, err
+			}
+		}
 	}
+
+	if err := app.DB.MarkPendingApplied(container.CleanName(), container.RemoteDigest); err != nil {
+		containerLogger.Err(err).Msg("Marking approved update as applied failed")
+	}
+
+	containerLogger.Info().Msg("Updated container")
+	app.Webhooks.ContainerUpdated(containerCtx, container, newContainer, hooks, updateWarnings...)
+	return newContainer, updateWarnings, nil
 }
 
-func (app Yacu) FetchUpdates(ctx context.Context) (yacucontainer.Containers, error) {
+// updates the canary and watches it for the soak period before the rest of its group is updated,
+// a failed update is already reported by UpdateContainer so only a failed soak is reported here
+func (app Yacu) UpdateCanary(ctx context.Context, canary *yacucontainer.Container, rest yacucontainer.Containers, run *summary.Summary) error {
+	logger := zerolog.Ctx(ctx).With().Str("service", "canary").Str("container", canary.Name).Str("image", canary.RepositoryFamiliarized()).Logger()
+	canaryCtx := logger.WithContext(context.Background())
+
+	logger.Info().Int("soak_time", app.Updater.Canary.SoakTime).Msg("Updating canary container")
+
+	newContainer, err := app.UpdateContainer(ctx, canary, run)
+	if err != nil {
+		return err
+	}
+
+	// stopped containers are not started after an update so there is nothing to watch
+	if !canary.IsRunning() {
+		return nil
+	}
+
+	if err := newContainer.Soak(canaryCtx, app.Client, time.Duration(app.Updater.Canary.SoakTime)*time.Second, app.Updater.Canary.MaxRestarts); err != nil {
+		logger.Warn().Err(err).Msg("Canary degraded")
+		app.Webhooks.ContainerError(ctx, newContainer, fmt.Sprintf("Canary failed, halted rollout to %s", strings.Join(rest.Names(), ", ")), err)
+		return err
+	}
+
+	logger.Info().Msg("Canary healthy, continuing rollout")
+	return nil
+}
+
+// finds the containers with updates, containers that cannot be scanned or checked are added to the run as failed
+func (app Yacu) FetchUpdates(ctx context.Context, run *summary.Summary) (yacucontainer.Containers, error) {
 	logger := zerolog.Ctx(ctx).With().Str("service", "scanner").Logger()
 	ctx = logger.WithContext(context.Background())
 
 	// List all containers
-	cntList, err := app.Client.ContainerList(
-		context.Background(),
-		types.ContainerListOptions{},
-	)
+	cntList, err := retry.Value(ctx, app.Retry.Docker, func() ([]types.Container, error) {
+		return app.Client.ContainerList(context.Background(), types.ContainerListOptions{})
+	})
 
 	if err != nil {
 		logger.Err(err).Msg("ContainerList request failed")
@@ -256,44 +534,221 @@ func (app Yacu) FetchUpdates(ctx context.Context) (yacucontainer.Containers, err
 	}
 
 	containers := yacucontainer.Containers{}
-	for _, c := range cntList {
-		// fetch detailed info
-		ci, err := app.Client.ContainerInspect(context.Background(), c.ID)
-		if err != nil {
-			logger.Err(err).Str("id", c.ID).Msg("ContainerInspect request failed")
-			return nil, fmt.Errorf("inspecting container %s failed: %w", c.ID, err)
-		}
+	scanned := yacucontainer.Containers{}
+	// states are published with the remote data fetched during the scan
+	defer func() {
+		app.PublishStates(ctx, scanned)
+	}()
 
-		container, err := yacucontainer.New(app.Client, &ci, app.Updater.StopTimeout, app.Scanner.ImageAge)
-		if err != nil {
-			if errors.Is(err, yacutypes.ErrRepositoryNotTagged) {
-				// skip over any repositories that use digests as there are no updates for those
-				continue
-			} else {
-				logger.Err(err).Str("container", ci.Name).Msg("Container initialization failed")
-				return nil, fmt.Errorf("initializing container %s failed: %w", ci.Name, err)
-			}
+	for _, c := range cntList {
+		container, err := app.scanContainer(ctx, c, run)
+		if err != nil || container == nil {
+			continue
 		}
 
 		// checks labels and config related flags
 		if !container.ShouldScan(app.Scanner.ScanAll, app.Scanner.ScanStopped) {
 			continue
 		}
+		scanned = append(scanned, container)
+
+		if yes, err := app.CheckForUpdate(ctx, container, run); errors.Is(err, yacuregistry.ErrRegistrySkipped) {
+			// already notified about once when the registry was skipped
+			run.AddSkipped(container.CleanName(), container.RepositoryFamiliarized(), err.Error())
+		} else if err != nil {
+			app.Webhooks.ImageError(ctx, container.Image, "Unable to check for updates", err)
+			run.AddFailed(summary.STAGE_CHECK, container.CleanName(), container.RepositoryFamiliarized(), "Unable to check for updates", err)
+		} else if yes {
+			containers = append(containers, container)
+		} else {
+			run.AddChecked(container.CleanName(), container.RepositoryFamiliarized())
+		}
+	}
 
-		if yes, err := container.IsOutdated(); err != nil {
-			return nil, err
+	return containers, nil
+}
+
+// decides whether the container should be updated now, updates that need an approval are requested instead
+func (app Yacu) CheckForUpdate(ctx context.Context, container *yacucontainer.Container, run *summary.Summary) (bool, error) {
+	requiresApproval := container.RequiresApproval(app.Approval.Enabled)
+	if requiresApproval {
+		// approved updates skip the checks as they were already done when the update was found
+		if yes, err := app.IsUpdateApproved(ctx, container); err != nil || yes {
+			return yes, err
+		}
+	}
+
+	if yes, err := container.IsOutdated(app.ageSource(ctx, container) == config.AGE_SOURCE_FIRST_SEEN); err != nil || !yes {
+		return false, err
+	}
+
+	if yes, err := app.IsRemotePullable(ctx, container); err != nil || !yes {
+		return false, err
+	}
+
+	if requiresApproval {
+		return false, app.RequestApproval(ctx, container, run)
+	}
+	return true, nil
+}
+
+func (app Yacu) FetchApprovedUpdates(ctx context.Context, run *summary.Summary) (yacucontainer.Containers, error) {
+	logger := zerolog.Ctx(ctx).With().Str("service", "scanner").Logger()
+	ctx = logger.WithContext(context.Background())
+
+	cntList, err := retry.Value(ctx, app.Retry.Docker, func() ([]types.Container, error) {
+		return app.Client.ContainerList(context.Background(), types.ContainerListOptions{All: true})
+	})
+
+	if err != nil {
+		logger.Err(err).Msg("ContainerList request failed")
+		return nil, fmt.Errorf("listing containers failed: %w", err)
+	}
+
+	containers := yacucontainer.Containers{}
+	for _, c := range cntList {
+		container, err := app.scanContainer(ctx, c, run)
+		if err != nil || container == nil {
+			continue
+		}
+
+		if yes, err := app.IsUpdateApproved(ctx, container); err != nil {
+			app.Webhooks.ContainerError(ctx, container, "Unable to check for approved updates", err)
+			run.AddFailed(summary.STAGE_CHECK, container.CleanName(), container.RepositoryFamiliarized(), "Unable to check for approved updates", err)
 		} else if yes {
-			if yes, err = app.IsRemotePullable(ctx, container); err != nil {
-				return nil, err
-			} else if yes {
-				containers = append(containers, container)
-			}
+			containers = append(containers, container)
+		} else {
+			run.AddChecked(container.CleanName(), container.RepositoryFamiliarized())
 		}
 	}
 
 	return containers, nil
 }
 
+// inspects a listed container, failures are reported and added to the run, nil without error for untagged images
+func (app Yacu) scanContainer(ctx context.Context, c types.Container, run *summary.Summary) (*yacucontainer.Container, error) {
+	logger := zerolog.Ctx(ctx)
+
+	name := c.ID
+	if len(c.Names) > 0 {
+		name = strings.TrimPrefix(c.Names[0], "/")
+	}
+
+	// fetch detailed info
+	ci, err := retry.Value(ctx, app.Retry.Docker, func() (types.ContainerJSON, error) {
+		return app.Client.ContainerInspect(context.Background(), c.ID)
+	})
+	if err != nil {
+		logger.Err(err).Str("id", c.ID).Msg("ContainerInspect request failed")
+		err = fmt.Errorf("inspecting container %s failed: %w", name, err)
+		app.Webhooks.Error(ctx, fmt.Sprintf("Unable to scan container %s", name), err)
+		run.AddFailed(summary.STAGE_SCAN, name, c.Image, "Unable to inspect container", err)
+		return nil, err
+	}
+
+	container, err := yacucontainer.New(app.Client, &ci, app.Updater.StopTimeout, app.Scanner.ImageAge)
+	if err != nil {
+		// skip over any repositories that use digests as there are no updates for those
+		if errors.Is(err, yacutypes.ErrRepositoryNotTagged) {
+			return nil, nil
+		}
+
+		logger.Err(err).Str("container", ci.Name).Msg("Container initialization failed")
+		err = fmt.Errorf("initializing container %s failed: %w", name, err)
+		app.Webhooks.Error(ctx, fmt.Sprintf("Unable to scan container %s", name), err)
+		run.AddFailed(summary.STAGE_SCAN, name, c.Image, "Unable to initialize container", err)
+		return nil, err
+	}
+	return container, nil
+}
+
+// checks for an approved update that has not been applied yet and sets the approved digest as the update target
+func (app Yacu) IsUpdateApproved(ctx context.Context, container *yacucontainer.Container) (bool, error) {
+	logger := zerolog.Ctx(ctx).With().Str("container", container.Name).Logger()
+
+	updates, err := app.DB.GetPendingUpdates(database.PENDING_APPROVED)
+	if err != nil {
+		logger.Err(err).Msg("Fetching approved updates from local database failed")
+		return false, fmt.Errorf("fetching approved updates from local database failed: %w", err)
+	}
+
+	for _, update := range updates {
+		if update.Container != container.CleanName() {
+			continue
+		}
+
+		if container.HasRepoDigest(update.Digest) {
+			// already running the approved image, e.g. updated manually
+			if err := app.DB.UpdatePendingStatus(update.RowId, database.PENDING_APPLIED); err != nil {
+				logger.Err(err).Msg("Updating approved update in local database failed")
+			}
+			return false, nil
+		}
+
+		logger.Debug().Str("token", update.Token).Msg("Approved update added to update queue")
+		container.RemoteDigest = update.Digest
+		return true, nil
+	}
+	return false, nil
+}
+
+// stores the found update in the local database and notifies about it if it has not been seen yet,
+// only new holds are added to the run so waiting approvals are not reported again by every run
+func (app Yacu) RequestApproval(ctx context.Context, container *yacucontainer.Container, run *summary.Summary) error {
+	logger := zerolog.Ctx(ctx).With().Str("container", container.Name).Logger()
+
+	if _, err := app.DB.GetPendingUpdateFor(container.CleanName(), container.RemoteDigest); err == nil {
+		// already pending, approved or rejected
+		return nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		logger.Err(err).Msg("Fetching pending update from local database failed")
+		return fmt.Errorf("fetching pending update for %s from local database failed: %w", container.Name, err)
+	}
+
+	pending, err := app.DB.SavePendingUpdate(container.CleanName(), container.RepositoryFamiliarized(), container.RemoteDigest)
+	if err != nil {
+		logger.Err(err).Msg("Writing pending update to local database failed")
+		return fmt.Errorf("writing pending update for %s to local database failed: %w", container.Name, err)
+	}
+
+	logger.Info().Str("token", pending.Token).Msg("Update waiting for approval")
+	app.Webhooks.UpdatePending(ctx, container, pending)
+	run.AddHeld(pending.Container, pending.Image, fmt.Sprintf("waiting for approval, id %s", pending.Token))
+	return nil
+}
+
+// approves or rejects a pending update, approved updates are applied right away if configured
+func (app Yacu) ResolvePending(ctx context.Context, token string, approve bool) (*database.PendingUpdateRow, error) {
+	logger := zerolog.Ctx(ctx).With().Str("token", token).Logger()
+
+	pending, err := app.DB.GetPendingUpdateFromToken(token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("pending update %s not found", token)
+		}
+		logger.Err(err).Msg("Fetching pending update from local database failed")
+		return nil, fmt.Errorf("fetching pending update %s from local database failed: %w", token, err)
+	}
+
+	if pending.Status != database.PENDING_WAITING {
+		return nil, fmt.Errorf("update %s is already %s", token, pending.Status)
+	}
+
+	status := database.PENDING_REJECTED
+	if approve {
+		status = database.PENDING_APPROVED
+	}
+
+	if err := app.DB.UpdatePendingStatus(pending.RowId, status); err != nil {
+		logger.Err(err).Msg("Updating pending update in local database failed")
+		return nil, fmt.Errorf("updating pending update %s in local database failed: %w", token, err)
+	}
+	pending.Status = status
+
+	logger.Info().Str("container", pending.Container).Str("status", string(status)).Msg("Pending update resolved")
+	return pending, nil
+}
+
 func (app Yacu) IsRemotePullable(ctx context.Context, container *yacucontainer.Container) (bool, error) {
 	logger := zerolog.Ctx(ctx).With().
 		Str("container", container.Name).
@@ -302,22 +757,30 @@ func (app Yacu) IsRemotePullable(ctx context.Context, container *yacucontainer.C
 	ctx = logger.WithContext(context.Background())
 
 	familiarNameTagged := container.RepositoryFamiliarized()
+	domain := reference.Domain(container.Repository)
+	platform := container.Image.Platform()
 
-	dbImage, err := app.DB.GetRemoteImageFromName(familiarNameTagged)
+	dbImage, err := app.DB.GetRemoteImage(container.Repository.String(), platform)
 	if err != nil {
 		// image data not present
 		if errors.Is(err, sql.ErrNoRows) {
 			// fetch data from registry
-			remoteData, err := yacuregistry.GetImageDataFromRegistry(ctx, &app.Registries, container.Repository)
+			remoteData, err := app.FetchRemoteImage(ctx, container, nil)
 			if err != nil {
 				return false, err
 			}
 
+			created := time.Time{}
+			if remoteData.Created != nil {
+				created = *remoteData.Created
+			}
+
 			// write data to local database
 			if _, err := app.DB.SaveRemoteImage(
-				familiarNameTagged,
-				reference.Domain(container.Repository),
-				*remoteData.Created,
+				container.Repository.String(),
+				platform,
+				domain,
+				created,
 				remoteData.Digest,
 			); err != nil {
 				logger.Err(err).Msg("Writing remote image data to local database failed")
@@ -325,7 +788,9 @@ func (app Yacu) IsRemotePullable(ctx context.Context, container *yacucontainer.C
 			}
 
 			// recheck if container is old enough
-			if utils.DaysPassed(*remoteData.Created) < container.MinImageAge {
+			if yes, err := app.IsOldEnough(ctx, container, remoteData.Created, remoteData.Digest); err != nil {
+				return false, err
+			} else if !yes {
 				logger.Debug().Msg("Image up to date")
 				return false, nil
 			}
@@ -337,6 +802,7 @@ func (app Yacu) IsRemotePullable(ctx context.Context, container *yacucontainer.C
 
 			// outdated
 			logger.Debug().Msg("Image added to update queue")
+			container.RemoteDigest = remoteData.Digest
 			return true, nil
 		} else {
 			// unknown SQL error
@@ -346,19 +812,33 @@ func (app Yacu) IsRemotePullable(ctx context.Context, container *yacucontainer.C
 	}
 
 	// check if image data in database is old enough
-	if utils.DaysPassed(dbImage.Created) < container.MinImageAge {
-		logger.Debug().Msg("Image up to date")
-		return false, nil
+	var dbCreated *time.Time = nil
+	if !dbImage.Created.IsZero() {
+		dbCreated = &dbImage.Created
 	}
 
-	// last check should have been done at least an interval enough ago
-	if utils.DaysPassed(dbImage.LastCheck) < container.MinImageAge {
+	if yes, err := app.IsOldEnough(ctx, container, dbCreated, dbImage.Digest); err != nil {
+		return false, err
+	} else if !yes {
 		logger.Debug().Msg("Image up to date")
 		return false, nil
 	}
 
-	// fetch new data from registry
-	remoteData, err := yacuregistry.GetImageDataFromRegistry(ctx, &app.Registries, container.Repository)
+	// an already known update is rechecked every run, otherwise the registry is only asked once the image is due
+	if container.HasRepoDigest(dbImage.Digest) {
+		nextCheck, err := app.NextCheck(ctx, dbImage)
+		if err != nil {
+			logger.Err(err).Msg("Scheduling remote image check failed")
+			return false, err
+		}
+		if time.Now().Before(nextCheck) {
+			logger.Debug().Time("next_check", nextCheck).Msg("Image up to date")
+			return false, nil
+		}
+	}
+
+	// fetch new data from registry, the image config only if the digest changed
+	remoteData, err := app.FetchRemoteImage(ctx, container, dbImage)
 	if err != nil {
 		return false, err
 	}
@@ -369,18 +849,20 @@ func (app Yacu) IsRemotePullable(ctx context.Context, container *yacucontainer.C
 		return false, fmt.Errorf("writing remote image data (%s) to local database failed: %w", familiarNameTagged, err)
 	}
 
-	// recheck if image is old enough to pull
-	if utils.DaysPassed(*remoteData.Created) < container.MinImageAge {
-		logger.Debug().Msg("Image up to date")
-		return false, nil
-	}
-
 	// update last check time
 	if err := app.DB.UpdateRemoteImageCheck(dbImage.RowId); err != nil {
 		logger.Err(err).Msg("Updating remote image data in local database failed")
 		return false, fmt.Errorf("updating remote image data (%s) in local database failed: %w", familiarNameTagged, err)
 	}
 
+	// recheck if image is old enough to pull
+	if yes, err := app.IsOldEnough(ctx, container, remoteData.Created, remoteData.Digest); err != nil {
+		return false, err
+	} else if !yes {
+		logger.Debug().Msg("Image up to date")
+		return false, nil
+	}
+
 	// check if remote and local images are different
 	if container.HasRepoDigest(remoteData.Digest) {
 		return false, nil
@@ -388,44 +870,134 @@ func (app Yacu) IsRemotePullable(ctx context.Context, container *yacucontainer.C
 
 	// outdated
 	logger.Debug().Msg("Image added to update queue")
+	container.RemoteDigest = remoteData.Digest
 	return true, nil
 }
 
-func (app Yacu) IsLatestImagePresent(ctx context.Context, named reference.NamedTagged) (bool, error) {
+// fetches the remote image data with retries, a registry failing repeatedly is skipped for the rest of the run
+func (app Yacu) FetchRemoteImage(ctx context.Context, container *yacucontainer.Container, known *database.RemoteImageRow) (*yacuregistry.ImageData, error) {
+	domain := reference.Domain(container.Repository)
+	if err := app.breaker.Allow(domain); err != nil {
+		return nil, err
+	}
+
+	remoteData, err := app.fetchRemoteImage(ctx, container, known)
+	if err == nil {
+		if err := app.DB.SaveDigestSeen(container.Repository.String(), remoteData.Digest); err != nil {
+			zerolog.Ctx(ctx).Err(err).Msg("Writing digest history to local database failed")
+		}
+	}
+
+	if app.breaker.Record(domain, err) {
+		zerolog.Ctx(ctx).Warn().Err(err).Str("registry", domain).Msg("Registry keeps failing, skipping it for the rest of the run")
+		app.Webhooks.Error(ctx, fmt.Sprintf("Registry %s is unreachable, its images are not checked until the next run", domain), err)
+	}
+	return remoteData, err
+}
+
+// checks the digest with a HEAD request first, the image config is only fetched when it differs from the known one
+func (app Yacu) fetchRemoteImage(ctx context.Context, container *yacucontainer.Container, known *database.RemoteImageRow) (*yacuregistry.ImageData, error) {
 	logger := zerolog.Ctx(ctx)
 
-	currentImgData, _, err := app.Client.ImageInspectWithRaw(context.Background(), named.String())
-	if err != nil {
-		logger.Err(err).Msg("InspectImage request failed")
-		return false, fmt.Errorf("inspecting image %s failed: %w", named.String(), err)
+	if known != nil {
+		remoteDigest, err := retry.Value(ctx, app.Retry.Registry, func() (digest.Digest, error) {
+			return yacuregistry.GetRemoteDigest(ctx, &app.Registries, container.Repository)
+		})
+
+		if err == nil && remoteDigest == known.Digest {
+			logger.Debug().Msg("Remote digest unchanged")
+
+			remoteData := &yacuregistry.ImageData{Digest: known.Digest}
+			if !known.Created.IsZero() {
+				remoteData.Created = &known.Created
+			}
+			return remoteData, nil
+		}
+
+		// registries that do not answer HEAD requests with a digest get the full check
+		if err != nil && retry.IsTransient(err) {
+			return nil, err
+		}
 	}
 
-	familiarNameTagged := utils.FamiliarTagged(named)
+	return retry.Value(ctx, app.Retry.Registry, func() (*yacuregistry.ImageData, error) {
+		return yacuregistry.GetImageDataFromRegistry(ctx, &app.Registries, container.Repository, container.Image.Platform())
+	})
+}
+
+// judges the age of a remote image by its created time, the time yacu first saw its digest or the later of both
+func (app Yacu) IsOldEnough(ctx context.Context, container *yacucontainer.Container, created *time.Time, digest digest.Digest) (bool, error) {
+	logger := zerolog.Ctx(ctx)
+
+	familiarNameTagged := container.RepositoryFamiliarized()
 
-	dbImage, err := app.DB.GetRemoteImageFromName(familiarNameTagged)
+	firstSeen, err := app.DB.GetDigestFirstSeen(container.Repository.String(), digest)
 	if err != nil {
-		logger.Err(err).Msg("Fetching remote image data from local database failed")
-		return false, fmt.Errorf("fetching remote image data (%s) from local database failed: %w", named.String(), err)
+		logger.Err(err).Msg("Fetching digest first seen time from local database failed")
+		return false, fmt.Errorf("fetching first seen time of %s (%s) from local database failed: %w", familiarNameTagged, digest, err)
 	}
 
-	if createdTime, err := time.Parse(time.RFC3339Nano, currentImgData.Created); err != nil {
-		logger.Err(err).Str("time", currentImgData.Created).Msg("unknown time format")
-		return false, fmt.Errorf("unknown time format %s: %w", currentImgData.Created, err)
-	} else if createdTime.Compare(dbImage.Created) == 0 {
-		return true, nil
+	released := releaseTime(app.ageSource(ctx, container), created, *firstSeen)
+	return utils.DaysPassed(released) >= container.MinImageAge, nil
+}
+
+func (app Yacu) ageSource(ctx context.Context, container *yacucontainer.Container) string {
+	source := container.AgeSource(app.Scanner.AgeSource)
+	if !config.IsAgeSource(source) {
+		zerolog.Ctx(ctx).Warn().Str("age_source", source).Msg("Invalid age source label, using configured one")
+		return app.Scanner.AgeSource
+	}
+	return source
+}
+
+func releaseTime(source string, created *time.Time, firstSeen time.Time) time.Time {
+	switch source {
+	case config.AGE_SOURCE_FIRST_SEEN:
+		return firstSeen
+	case config.AGE_SOURCE_MAX:
+		if created != nil && created.After(firstSeen) {
+			return *created
+		}
+		return firstSeen
+	default:
+		// images without a created time can only be judged by when they were first seen
+		if created == nil {
+			return firstSeen
+		}
+		return *created
+	}
+}
+
+func (app Yacu) IsLatestImagePresent(ctx context.Context, named reference.NamedTagged, digest digest.Digest) (bool, error) {
+	logger := zerolog.Ctx(ctx)
+
+	currentImgData, err := retry.Value(ctx, app.Retry.Docker, func() (types.ImageInspect, error) {
+		data, _, err := app.Client.ImageInspectWithRaw(context.Background(), named.String())
+		return data, err
+	})
+	if err != nil {
+		logger.Err(err).Msg("InspectImage request failed")
+		return false, fmt.Errorf("inspecting image %s failed: %w", named.String(), err)
 	}
 
 	for _, str := range currentImgData.RepoDigests {
-		if strings.Contains(str, dbImage.Digest.String()) {
+		if strings.Contains(str, digest.String()) {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
-func (app Yacu) PullImage(ctx context.Context, repository reference.NamedTagged) error {
+// pulls the exact digest and tags it so that the tag cannot move to an unchecked image in between
+func (app Yacu) PullImage(ctx context.Context, repository reference.NamedTagged, digest digest.Digest) error {
 	logger := zerolog.Ctx(ctx)
 
+	canonical, err := reference.WithDigest(reference.TrimNamed(repository), digest)
+	if err != nil {
+		logger.Err(err).Str("digest", digest.String()).Msg("Invalid image digest")
+		return fmt.Errorf("invalid image digest %s: %w", digest, err)
+	}
+
 	pullOptions := types.ImagePullOptions{}
 	if authEntry := app.Registries.GetAuthConfigFor(reference.Domain(repository)); authEntry != nil {
 		auth, err := registry.EncodeAuthConfig(
@@ -443,22 +1015,35 @@ func (app Yacu) PullImage(ctx context.Context, repository reference.NamedTagged)
 		pullOptions.RegistryAuth = auth
 	}
 
-	response, err := app.Client.ImagePull(
-		context.Background(),
-		repository.String(),
-		pullOptions,
-	)
+	// an interrupted pull continues with the layers already downloaded
+	if err := retry.Do(ctx, app.Retry.Docker, func() error {
+		response, err := app.Client.ImagePull(
+			context.Background(),
+			canonical.String(),
+			pullOptions,
+		)
 
-	if err != nil {
-		logger.Err(err).Msg("Failed to pull image")
-		return fmt.Errorf("failed to pull image: %w", err)
-	}
+		if err != nil {
+			logger.Err(err).Msg("Failed to pull image")
+			return fmt.Errorf("failed to pull image: %w", err)
+		}
 
-	defer response.Close()
+		defer response.Close()
 
-	if _, err := io.ReadAll(response); err != nil {
-		logger.Err(err).Msg("Failure while pulling image")
-		return fmt.Errorf("failure while pulling image: %w", err)
+		if _, err := io.ReadAll(response); err != nil {
+			logger.Err(err).Msg("Failure while pulling image")
+			return fmt.Errorf("failure while pulling image: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := retry.Do(ctx, app.Retry.Docker, func() error {
+		return app.Client.ImageTag(context.Background(), canonical.String(), repository.String())
+	}); err != nil {
+		logger.Err(err).Msg("Failed to tag pulled image")
+		return fmt.Errorf("failed to tag pulled image: %w", err)
 	}
 
 	return nil
@@ -469,19 +1054,21 @@ func (app Yacu) GetDependingContainers(ctx context.Context, dependsOn *types.Con
 	logger.Debug().Msg("Fetching depending containers")
 
 	// list all containers with the compose label
-	containers, err := app.Client.ContainerList(
-		context.Background(),
-		types.ContainerListOptions{
-			Filters: filters.NewArgs(
-				filters.KeyValuePair{
-					Key: "label", Value: yacucontainer.LABEL_DEPENDS_ON,
-				},
-				filters.KeyValuePair{
-					Key: "status", Value: "running",
-				},
-			),
-		},
-	)
+	containers, err := retry.Value(ctx, app.Retry.Docker, func() ([]types.Container, error) {
+		return app.Client.ContainerList(
+			context.Background(),
+			types.ContainerListOptions{
+				Filters: filters.NewArgs(
+					filters.KeyValuePair{
+						Key: "label", Value: yacucontainer.LABEL_DEPENDS_ON,
+					},
+					filters.KeyValuePair{
+						Key: "status", Value: "running",
+					},
+				),
+			},
+		)
+	})
 	if err != nil {
 		logger.Err(err).Msg("ContainerList request failed")
 		return nil, fmt.Errorf("listing containers failed: %w", err)
@@ -533,12 +1120,12 @@ func (app Yacu) GetDependingContainers(ctx context.Context, dependsOn *types.Con
 	return dependantContainers, nil
 }
 
-func (app Yacu) RemoveUnusedImages(ctx context.Context, images ...*image.ImageData) (count int) {
+func (app Yacu) RemoveUnusedImages(ctx context.Context, run *summary.Summary, images ...*image.ImageData) (count int) {
 	logger := zerolog.Ctx(ctx)
 
-	containers, err := app.Client.ContainerList(
-		context.Background(), types.ContainerListOptions{},
-	)
+	containers, err := retry.Value(ctx, app.Retry.Docker, func() ([]types.Container, error) {
+		return app.Client.ContainerList(context.Background(), types.ContainerListOptions{})
+	})
 
 	if err != nil {
 		logger.Err(err).Msg("ContainerList request failed")
@@ -572,8 +1159,10 @@ func (app Yacu) RemoveUnusedImages(ctx context.Context, images ...*image.ImageDa
 			if err != nil {
 				imageLogger.Err(err).Msg("Removing image failed")
 				app.Webhooks.ImageRemovalFailed(imageCtx, image, err)
+				run.AddFailed(summary.STAGE_CLEANUP, utils.FamiliarTagged(image.Repository), utils.FamiliarTagged(image.Repository), "Unable to remove image", err)
 			} else {
 				count += 1
+				run.AddRemovedImage(image.Raw.Size)
 				imageLogger.Debug().Str("response", fmt.Sprintf("%v", response)).Msg("Unused image removed")
 			}
 		}
@@ -0,0 +1,308 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/config"
+
+	yacucontainer "github.com/terrails/yacu/types/container"
+)
+
+const (
+	idFormat     = "20060102-150405"
+	manifestName = "backup.json"
+)
+
+var anonymousVolume = regexp.MustCompile("^[0-9a-f]{64}$")
+
+type Backup struct {
+	Id        string         `json:"id"`
+	Container string         `json:"container"`
+	Image     string         `json:"image"`
+	Created   time.Time      `json:"created"`
+	Volumes   []VolumeBackup `json:"volumes"`
+}
+
+type VolumeBackup struct {
+	Name        string `json:"name"`
+	Destination string `json:"destination"`
+	Anonymous   bool   `json:"anonymous"`
+	File        string `json:"file"`
+}
+
+// archives every named and anonymous volume of the stopped container into the backup directory, returns nil if there are none
+func Create(ctx context.Context, client *client.Client, cfg config.Backup, data *types.ContainerJSON) (*Backup, error) {
+	name := strings.TrimPrefix(data.Name, "/")
+	logger := zerolog.Ctx(ctx).With().Str("service", "backup").Str("container", name).Logger()
+
+	volumes := 0
+	for _, mnt := range data.Mounts {
+		if mnt.Type == mount.TypeVolume {
+			volumes += 1
+		}
+	}
+
+	// nothing to back up
+	if volumes == 0 {
+		return nil, nil
+	}
+
+	created := time.Now()
+	backup := &Backup{
+		Id:        created.Format(idFormat),
+		Container: name,
+		Image:     data.Config.Image,
+		Created:   created,
+		Volumes:   []VolumeBackup{},
+	}
+
+	directory, err := filepath.Abs(filepath.Join(cfg.Directory, name, backup.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(directory, 0744); err != nil {
+		logger.Err(err).Msg("Creating backup directory failed")
+		return nil, fmt.Errorf("creating backup directory %s failed: %w", directory, err)
+	}
+
+	for _, mnt := range data.Mounts {
+		if mnt.Type != mount.TypeVolume {
+			continue
+		}
+
+		volume := VolumeBackup{
+			Name:        mnt.Name,
+			Destination: mnt.Destination,
+			Anonymous:   anonymousVolume.MatchString(mnt.Name),
+			File:        fmt.Sprintf("%s.tar.gz", mnt.Name),
+		}
+
+		logger.Debug().Str("volume", mnt.Name).Str("destination", mnt.Destination).Msg("Archiving volume")
+		if err := runHelper(ctx, client, cfg.Image,
+			[]string{fmt.Sprintf("%s:/source:ro", mnt.Name), fmt.Sprintf("%s:/backup", directory)},
+			[]string{"tar", "czf", "/backup/" + volume.File, "-C", "/source", "."},
+		); err != nil {
+			os.RemoveAll(directory)
+			return nil, fmt.Errorf("archiving volume %s failed: %w", mnt.Name, err)
+		}
+
+		backup.Volumes = append(backup.Volumes, volume)
+	}
+
+	manifest, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(directory, manifestName), manifest, 0644); err != nil {
+		os.RemoveAll(directory)
+		logger.Err(err).Msg("Writing backup manifest failed")
+		return nil, fmt.Errorf("writing backup manifest failed: %w", err)
+	}
+
+	logger.Info().Str("backup", backup.Id).Int("volumes", len(backup.Volumes)).Msg("Volumes backed up")
+	return backup, nil
+}
+
+// extracts the backup into the volumes mounted at the same destinations, the container has to be stopped
+func Restore(ctx context.Context, client *client.Client, cfg config.Backup, data *types.ContainerJSON, id string) error {
+	name := strings.TrimPrefix(data.Name, "/")
+	logger := zerolog.Ctx(ctx).With().Str("service", "backup").Str("container", name).Str("backup", id).Logger()
+
+	backup, err := Get(cfg, name, id)
+	if err != nil {
+		return err
+	}
+
+	directory, err := filepath.Abs(filepath.Join(cfg.Directory, name, backup.Id))
+	if err != nil {
+		return err
+	}
+
+	for _, volume := range backup.Volumes {
+		// anonymous volumes get a new name each time the container is recreated so match them by destination
+		target := ""
+		for _, mnt := range data.Mounts {
+			if mnt.Type == mount.TypeVolume && mnt.Destination == volume.Destination {
+				target = mnt.Name
+				break
+			}
+		}
+
+		if len(target) == 0 {
+			return fmt.Errorf("container %s has no volume mounted at %s", name, volume.Destination)
+		}
+
+		logger.Debug().Str("volume", target).Str("destination", volume.Destination).Msg("Restoring volume")
+		if err := runHelper(ctx, client, cfg.Image,
+			[]string{fmt.Sprintf("%s:/target", target), fmt.Sprintf("%s:/backup:ro", directory)},
+			[]string{"sh", "-c", fmt.Sprintf("find /target -mindepth 1 -delete && tar xzf '/backup/%s' -C /target", volume.File)},
+		); err != nil {
+			return fmt.Errorf("restoring volume %s failed: %w", target, err)
+		}
+	}
+
+	logger.Info().Int("volumes", len(backup.Volumes)).Msg("Volumes restored")
+	return nil
+}
+
+func Get(cfg config.Backup, container, id string) (*Backup, error) {
+	file, err := os.ReadFile(filepath.Join(cfg.Directory, container, id, manifestName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("backup %s of container %s not found", id, container)
+		}
+		return nil, err
+	}
+
+	var backup Backup
+	if err := json.Unmarshal(file, &backup); err != nil {
+		return nil, fmt.Errorf("reading backup manifest failed: %w", err)
+	}
+	return &backup, nil
+}
+
+// returns the backups of a container, newest first
+func List(cfg config.Backup, container string) ([]*Backup, error) {
+	entries, err := os.ReadDir(filepath.Join(cfg.Directory, container))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*Backup{}, nil
+		}
+		return nil, err
+	}
+
+	backups := []*Backup{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if backup, err := Get(cfg, container, entry.Name()); err == nil {
+			backups = append(backups, backup)
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Created.After(backups[j].Created)
+	})
+	return backups, nil
+}
+
+// removes backups exceeding the configured count or age, the newest backup is always kept
+func Prune(ctx context.Context, cfg config.Backup, container string) (count int) {
+	logger := zerolog.Ctx(ctx).With().Str("service", "backup").Str("container", container).Logger()
+
+	backups, err := List(cfg, container)
+	if err != nil {
+		logger.Err(err).Msg("Listing backups failed")
+		return
+	}
+
+	for i, backup := range backups {
+		if i == 0 {
+			continue
+		}
+
+		tooMany := cfg.Keep > 0 && i >= cfg.Keep
+		tooOld := cfg.MaxAge > 0 && time.Since(backup.Created) > time.Duration(cfg.MaxAge)*24*time.Hour
+		if !tooMany && !tooOld {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(cfg.Directory, container, backup.Id)); err != nil {
+			logger.Err(err).Str("backup", backup.Id).Msg("Removing backup failed")
+			continue
+		}
+		count += 1
+		logger.Debug().Str("backup", backup.Id).Msg("Removed backup")
+	}
+	return
+}
+
+// runs a short-lived container with the given binds and waits for it to exit successfully
+func runHelper(ctx context.Context, client *client.Client, image string, binds []string, cmd []string) error {
+	logger := zerolog.Ctx(ctx)
+
+	if _, _, err := client.ImageInspectWithRaw(context.Background(), image); err != nil {
+		logger.Debug().Str("image", image).Msg("Pulling backup helper image")
+
+		response, err := client.ImagePull(context.Background(), image, types.ImagePullOptions{})
+		if err != nil {
+			logger.Err(err).Msg("Failed to pull backup helper image")
+			return fmt.Errorf("failed to pull backup helper image %s: %w", image, err)
+		}
+		defer response.Close()
+
+		if _, err := io.ReadAll(response); err != nil {
+			logger.Err(err).Msg("Failure while pulling backup helper image")
+			return fmt.Errorf("failure while pulling backup helper image %s: %w", image, err)
+		}
+	}
+
+	created, err := client.ContainerCreate(
+		context.Background(),
+		&container.Config{
+			Image: image,
+			Cmd:   cmd,
+			// never scan the helper itself
+			Labels: map[string]string{yacucontainer.LABEL_ENABLE: "false"},
+		},
+		&container.HostConfig{
+			Binds: binds,
+		},
+		nil,
+		nil,
+		"",
+	)
+	if err != nil {
+		logger.Err(err).Msg("Failed to create backup helper container")
+		return fmt.Errorf("failed to create backup helper container: %w", err)
+	}
+
+	defer client.ContainerRemove(context.Background(), created.ID, types.ContainerRemoveOptions{Force: true})
+
+	respCh, errCh := client.ContainerWait(context.Background(), created.ID, container.WaitConditionNextExit)
+
+	if err := client.ContainerStart(context.Background(), created.ID, types.ContainerStartOptions{}); err != nil {
+		logger.Err(err).Msg("Failed to start backup helper container")
+		return fmt.Errorf("failed to start backup helper container: %w", err)
+	}
+
+	select {
+	case err := <-errCh:
+		logger.Err(err).Msg("An error occurred while sending or receiving a ContainerWait request")
+		return fmt.Errorf("waiting on backup helper container failed: %w", err)
+	case resp := <-respCh:
+		if resp.Error != nil {
+			return fmt.Errorf("waiting on backup helper container failed: %s", resp.Error.Message)
+		}
+
+		if resp.StatusCode != 0 {
+			var output bytes.Buffer
+			if logs, err := client.ContainerLogs(context.Background(), created.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true}); err == nil {
+				stdcopy.StdCopy(&output, &output, logs)
+				logs.Close()
+			}
+			return fmt.Errorf("backup helper exited with code %d: %s", resp.StatusCode, strings.TrimSpace(output.String()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package config
+
+import "strings"
+
+type Api struct {
+	Listen string `yaml:"listen"`
+	Token  string `yaml:"token"`
+	// serve the api without a token, anyone reaching it can approve updates
+	Insecure bool `yaml:"insecure"`
+}
+
+// a token is required unless authentication is explicitly disabled
+func (a Api) IsAuthValid() bool {
+	return len(strings.TrimSpace(a.Token)) > 0 || a.Insecure
+}
@@ -0,0 +1,6 @@
+package config
+
+type Approval struct {
+	Enabled          bool `yaml:"enabled"`
+	ApplyImmediately bool `yaml:"apply_immediately"`
+}
@@ -0,0 +1,9 @@
+package config
+
+type Backup struct {
+	Enabled   bool   `yaml:"enabled"`
+	Directory string `yaml:"directory"`
+	Keep      int    `yaml:"keep"`
+	MaxAge    int    `yaml:"max_age"`
+	Image     string `yaml:"image"`
+}
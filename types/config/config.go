@@ -13,8 +13,17 @@ type Config struct {
 	Logging    LoggingConfig   `yaml:"logging"`
 	Scanner    Scanner         `yaml:"scanner"`
 	Updater    Updater         `yaml:"updater"`
+	Retry      Retry           `yaml:"retry"`
+	Backup     Backup          `yaml:"backup"`
+	Approval   Approval        `yaml:"approval"`
+	Api        Api             `yaml:"api"`
+	Mqtt       Mqtt            `yaml:"mqtt"`
 	Registries RegistryEntries `yaml:"registries"`
 	Webhooks   Webhooks        `yaml:"webhooks"`
+	Notify     Notify          `yaml:"notify"`
+	Routes     Routes          `yaml:"routes"`
+
+	Notifications Notifications `yaml:"notifications"`
 }
 
 func GetDefaultConfig() *Config {
@@ -34,16 +43,70 @@ func GetDefaultConfig() *Config {
 		Scanner: Scanner{
 			Interval:    "@weekly",
 			ImageAge:    7,
+			AgeSource:   AGE_SOURCE_CREATED,
 			ScanAll:     false,
 			ScanStopped: false,
+
+			MinCheckInterval: 6,
+			MaxCheckInterval: 168,
 		},
 		Updater: Updater{
-			StopTimeout:   30,
-			RemoveVolumes: false,
-			RemoveImages:  false,
+			StopTimeout:     30,
+			RemoveVolumes:   false,
+			RemoveImages:    false,
+			VerifyTimeout:   60,
+			VerifyWatch:     0,
+			HookTimeout:     300,
+			QuarantineAfter: 3,
+			Canary: Canary{
+				Enabled:     false,
+				SoakTime:    300,
+				MaxRestarts: 0,
+			},
+		},
+		Retry: Retry{
+			Registry: RetryPolicy{
+				Attempts: 3,
+				Delay:    2,
+				MaxDelay: 30,
+			},
+			Docker: RetryPolicy{
+				Attempts: 3,
+				Delay:    1,
+				MaxDelay: 10,
+			},
+			CircuitBreaker: 3,
+		},
+		Backup: Backup{
+			Enabled:   false,
+			Directory: "backups",
+			Keep:      5,
+			MaxAge:    30,
+			Image:     "alpine:latest",
+		},
+		Approval: Approval{
+			Enabled:          false,
+			ApplyImmediately: false,
+		},
+		Api: Api{
+			Listen: "",
+			Token:  "",
+		},
+		Mqtt: Mqtt{
+			Broker:          "",
+			ClientId:        "yacu",
+			Topic:           "yacu",
+			DiscoveryPrefix: "homeassistant",
 		},
 		Registries: RegistryEntries{},
 		Webhooks:   Webhooks{},
+		Notify:     Notify{},
+		Routes:     Routes{},
+		Notifications: Notifications{
+			DedupPeriod: 24,
+			Resolved:    true,
+			Retries:     10,
+		},
 	}
 }
 
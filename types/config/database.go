@@ -16,14 +16,23 @@ type DatabaseConfig struct {
 	Path string
 }
 
-func (c DatabaseConfig) LoadDatabase(ctx context.Context) (*database.Database, error) {
+// lock file next to the database, shared by every yacu process using it
+func (c DatabaseConfig) LockPath() string {
+	return c.path() + ".lock"
+}
+
+func (c DatabaseConfig) path() string {
 	if len(strings.TrimSpace(c.Path)) == 0 {
-		c.Path = "yacu.db"
+		return "yacu.db"
 	}
+	return c.Path
+}
+
+func (c DatabaseConfig) LoadDatabase(ctx context.Context) (*database.Database, error) {
+	c.Path = c.path()
 
 	logger := zerolog.Ctx(ctx).With().Str("service", "database").Str("path", c.Path).Logger()
 
-	createTables := false
 	if _, err := os.Stat(c.Path); errors.Is(err, os.ErrNotExist) {
 		file, err := os.Create(c.Path)
 		if err != nil {
@@ -31,8 +40,6 @@ func (c DatabaseConfig) LoadDatabase(ctx context.Context) (*database.Database, e
 			return nil, err
 		}
 		file.Close()
-
-		createTables = true
 	}
 
 	db, err := sql.Open("sqlite3", c.Path)
@@ -45,23 +52,10 @@ func (c DatabaseConfig) LoadDatabase(ctx context.Context) (*database.Database, e
 		DB: db,
 	}
 
-	// Create tables if file was just created
-	if createTables {
-
-		if _, err = database.Exec(
-			`CREATE TABLE remote_images (
-				id 				INTEGER PRIMARY KEY,
-				name 			TEXT NOT NULL,
-				domain	 		TEXT NOT NULL,
-				created			TEXT NOT NULL,
-				digest			TEXT NOT NULL,
-				last_check      TEXT NOT NULL,
-				unique (name, domain)
-			);`,
-		); err != nil {
-			logger.Err(err).Msg("creating remote_images table failed")
-			return nil, err
-		}
+	// create or upgrade tables
+	if err = database.Migrate(); err != nil {
+		logger.Err(err).Msg("migrating database failed")
+		return nil, err
 	}
 
 	return database, nil
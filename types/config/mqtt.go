@@ -0,0 +1,10 @@
+package config
+
+type Mqtt struct {
+	Broker          string `yaml:"broker"`
+	Username        string `yaml:"username"`
+	Password        string `yaml:"password"`
+	ClientId        string `yaml:"client_id"`
+	Topic           string `yaml:"topic"`
+	DiscoveryPrefix string `yaml:"discovery_prefix"`
+}
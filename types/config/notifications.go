@@ -0,0 +1,7 @@
+package config
+
+type Notifications struct {
+	DedupPeriod int  `yaml:"dedup_period"`
+	Resolved    bool `yaml:"resolved"`
+	Retries     int  `yaml:"retries"`
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// notification services configured with a single url each, e.g. discord://token@id
+type Notify []string
+
+// maps a notification url onto the webhook instance name and its settings, the name defaults to the service
+func ParseNotifyUrl(raw string) (string, *Webhook, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		// url errors quote the whole url including its credentials, only the service is named
+		if service, _, found := strings.Cut(raw, "://"); found {
+			return "", nil, fmt.Errorf("parsing %s notify url failed", service)
+		}
+		return "", nil, fmt.Errorf("parsing notify url failed")
+	}
+
+	query := parsed.Query()
+	hook := &Webhook{}
+	if err := parseFilters(hook, query); err != nil {
+		return "", nil, err
+	}
+
+	scheme := "https"
+	if disable, _ := parseBool(query.Get("disable_tls")); disable {
+		scheme = "http"
+	}
+	// path segments without the leading slash
+	segments := []string{}
+	for _, segment := range strings.Split(strings.Trim(parsed.Path, "/"), "/") {
+		if len(segment) > 0 {
+			segments = append(segments, segment)
+		}
+	}
+	password, _ := parsed.User.Password()
+
+	service := parsed.Scheme
+	switch service {
+	case "discord":
+		// discord://token@id
+		if len(parsed.User.Username()) == 0 || len(parsed.Host) == 0 {
+			return "", nil, fmt.Errorf("discord url should be discord://token@id")
+		}
+		hook.Url = fmt.Sprintf("https://discord.com/api/webhooks/%s/%s", parsed.Host, parsed.User.Username())
+		hook.Author.Name = query.Get("username")
+		hook.Author.IconUrl = query.Get("avatar")
+	case "slack":
+		// slack://A/B/C using the parts of the hooks.slack.com url
+		parts := append([]string{parsed.Host}, segments...)
+		if len(parts) != 3 {
+			return "", nil, fmt.Errorf("slack url should be slack://A/B/C")
+		}
+		hook.Url = "https://hooks.slack.com/services/" + strings.Join(parts, "/")
+		hook.Channel = query.Get("channel")
+		hook.Author.Name = query.Get("username")
+		hook.Author.IconUrl = query.Get("icon")
+	case "mattermost":
+		// mattermost://host[:port][/path]/token
+		if len(segments) == 0 {
+			return "", nil, fmt.Errorf("mattermost url should be mattermost://host/token")
+		}
+		prefix := strings.Join(segments[:len(segments)-1], "/")
+		if len(prefix) > 0 {
+			prefix = "/" + prefix
+		}
+		hook.Url = fmt.Sprintf("%s://%s%s/hooks/%s", scheme, parsed.Host, prefix, segments[len(segments)-1])
+		hook.Channel = query.Get("channel")
+		hook.Author.Name = query.Get("username")
+		hook.Author.IconUrl = query.Get("icon")
+	case "telegram":
+		// telegram://token@telegram?chat_id=, any other host is used as bot api server
+		hook.Token = parsed.User.String()
+		if unescaped, err := url.PathUnescape(hook.Token); err == nil {
+			hook.Token = unescaped
+		}
+		if parsed.Host != "telegram" {
+			hook.Url = fmt.Sprintf("%s://%s", scheme, parsed.Host)
+		}
+		hook.ChatId = query.Get("chat_id")
+		hook.Format = query.Get("format")
+		if thread := query.Get("thread_id"); len(thread) > 0 {
+			if hook.ThreadId, err = strconv.Atoi(thread); err != nil {
+				return "", nil, fmt.Errorf("invalid telegram thread_id %s", thread)
+			}
+		}
+	case "ntfy":
+		// ntfy://[token@]host[:port]/topic
+		if len(segments) != 1 {
+			return "", nil, fmt.Errorf("ntfy url should be ntfy://host/topic")
+		}
+		hook.Url = fmt.Sprintf("%s://%s", scheme, parsed.Host)
+		hook.Topic = segments[0]
+		hook.Token = parsed.User.Username()
+	case "gotify":
+		// gotify://host[:port][/path]/token
+		if len(segments) == 0 {
+			return "", nil, fmt.Errorf("gotify url should be gotify://host/token")
+		}
+		hook.Url = fmt.Sprintf("%s://%s/%s", scheme, parsed.Host, strings.Join(segments[:len(segments)-1], "/"))
+		hook.Token = segments[len(segments)-1]
+	case "smtp":
+		// smtp://[user:pass@]host[:port]/?from=&to=a,b
+		hook.Host = parsed.Hostname()
+		if port := parsed.Port(); len(port) > 0 {
+			if hook.Port, err = strconv.Atoi(port); err != nil {
+				return "", nil, fmt.Errorf("invalid smtp port %s", port)
+			}
+		}
+		hook.Username = parsed.User.Username()
+		hook.Password = password
+		hook.Security = query.Get("security")
+		hook.From = query.Get("from")
+		if to := query.Get("to"); len(to) > 0 {
+			hook.To = strings.Split(to, ",")
+		}
+	case "matrix":
+		// matrix://token@host[:port]/?room=
+		hook.Url = fmt.Sprintf("%s://%s", scheme, parsed.Host)
+		hook.Token = parsed.User.Username()
+		hook.Room = query.Get("room")
+		hook.Notice, _ = parseBool(query.Get("notice"))
+		if mentions := query.Get("mentions"); len(mentions) > 0 {
+			hook.Mentions = strings.Split(mentions, ",")
+		}
+	case "generic+http", "generic+https":
+		// generic+https://host/path, the query is passed on except for the filters
+		target := *parsed
+		target.Scheme = strings.TrimPrefix(service, "generic+")
+		target.RawQuery = withoutFilters(query).Encode()
+		hook.Url = target.String()
+		service = "generic"
+	default:
+		return "", nil, fmt.Errorf("unknown notify service %s", parsed.Scheme)
+	}
+
+	hook.Type = service
+	name := query.Get("name")
+	if len(name) == 0 {
+		name = service
+	}
+	return name, hook, nil
+}
+
+var filterKeys = []string{"name", "mode", "digest", "errors", "image_success", "container_success", "pending", "disable_tls"}
+
+// event filters shared by all services, e.g. ?errors=true&container_success=false&mode=summary
+func parseFilters(hook *Webhook, query url.Values) error {
+	hook.Mode = WebhookMode(query.Get("mode"))
+	hook.Digest = query.Get("digest")
+	if !hook.IsModeValid() {
+		return fmt.Errorf("unknown mode %s", hook.Mode)
+	}
+
+	for key, target := range map[string]**bool{
+		"errors":            &hook.Kind.Errors,
+		"image_success":     &hook.Kind.ImageSuccess,
+		"container_success": &hook.Kind.ContainerSuccess,
+		"pending":           &hook.Kind.Pending,
+	} {
+		if !query.Has(key) {
+			continue
+		}
+		value, err := parseBool(query.Get(key))
+		if err != nil {
+			return fmt.Errorf("invalid value %s for %s", query.Get(key), key)
+		}
+		*target = &value
+	}
+	return nil
+}
+
+func withoutFilters(query url.Values) url.Values {
+	filtered := url.Values{}
+	for key, values := range query {
+		filtered[key] = values
+	}
+	for _, key := range filterKeys {
+		filtered.Del(key)
+	}
+	return filtered
+}
+
+// also accepts yes and no
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
@@ -0,0 +1,29 @@
+package config
+
+import "time"
+
+type Retry struct {
+	Registry RetryPolicy `yaml:"registry"`
+	Docker   RetryPolicy `yaml:"docker"`
+	// consecutive failed checks after which a registry is skipped for the rest of the run, 0 disables skipping
+	CircuitBreaker int `yaml:"circuit_breaker"`
+}
+
+type RetryPolicy struct {
+	Attempts int     `yaml:"attempts"`  // attempts including the first one
+	Delay    float64 `yaml:"delay"`     // seconds before the first retry, doubled for every further one
+	MaxDelay float64 `yaml:"max_delay"` // upper bound of a single delay in seconds
+}
+
+// delay before the given retry, without jitter
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	delay := time.Duration(p.Delay * float64(time.Second))
+	limit := time.Duration(p.MaxDelay * float64(time.Second))
+	for i := 1; i < retry && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
@@ -0,0 +1,19 @@
+package config
+
+// routes send events to named webhooks instead of all of them
+type Routes []Route
+
+type Route struct {
+	Match  RouteMatch `yaml:"match"`
+	Notify []string   `yaml:"notify"`
+}
+
+// every set list has to match, a list matches if any of its entries does
+type RouteMatch struct {
+	Events     []string `yaml:"events"`
+	Severity   []string `yaml:"severity"`
+	Containers []string `yaml:"containers"`
+	Projects   []string `yaml:"projects"`
+	Images     []string `yaml:"images"`
+	Labels     []string `yaml:"labels"`
+}
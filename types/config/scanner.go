@@ -1,17 +1,52 @@
 package config
 
 import (
+	"time"
+
 	"github.com/adhocore/gronx"
 )
 
+const (
+	AGE_SOURCE_CREATED    string = "created"
+	AGE_SOURCE_FIRST_SEEN string = "first_seen"
+	AGE_SOURCE_MAX        string = "max"
+)
+
 type Scanner struct {
 	Interval    string `yaml:"interval"`
 	ImageAge    int    `yaml:"image_age"`
+	AgeSource   string `yaml:"age_source"`
 	ScanAll     bool   `yaml:"scan_all"`
 	ScanStopped bool   `yaml:"scan_stopped"`
+	// bounds in hours of the time between registry checks of an image, learned from how often its digest changes
+	MinCheckInterval int `yaml:"min_check_interval"`
+	MaxCheckInterval int `yaml:"max_check_interval"`
 }
 
 func (s Scanner) IsIntervalValid() bool {
 	gron := gronx.New()
 	return gron.IsValid(s.Interval)
 }
+
+func (s Scanner) IsCheckIntervalValid() bool {
+	return s.MinCheckInterval >= 0 && s.MaxCheckInterval >= s.MinCheckInterval
+}
+
+// limits the interval to the configured bounds
+func (s Scanner) ClampCheckInterval(interval time.Duration) time.Duration {
+	lower := time.Duration(s.MinCheckInterval) * time.Hour
+	upper := time.Duration(s.MaxCheckInterval) * time.Hour
+	return min(max(interval, lower), upper)
+}
+
+func (s Scanner) IsAgeSourceValid() bool {
+	return IsAgeSource(s.AgeSource)
+}
+
+func IsAgeSource(source string) bool {
+	switch source {
+	case AGE_SOURCE_CREATED, AGE_SOURCE_FIRST_SEEN, AGE_SOURCE_MAX:
+		return true
+	}
+	return false
+}
@@ -1,7 +1,18 @@
 package config
 
 type Updater struct {
-	StopTimeout   int  `yaml:"stop_timeout"`
-	RemoveVolumes bool `yaml:"remove_volumes"`
-	RemoveImages  bool `yaml:"remove_images"`
+	StopTimeout     int    `yaml:"stop_timeout"`
+	RemoveVolumes   bool   `yaml:"remove_volumes"`
+	RemoveImages    bool   `yaml:"remove_images"`
+	VerifyTimeout   int    `yaml:"verify_timeout"`
+	VerifyWatch     int    `yaml:"verify_watch"`
+	HookTimeout     int    `yaml:"hook_timeout"`
+	QuarantineAfter int    `yaml:"quarantine_after"`
+	Canary          Canary `yaml:"canary"`
+}
+
+type Canary struct {
+	Enabled     bool `yaml:"enabled"`
+	SoakTime    int  `yaml:"soak_time"`
+	MaxRestarts int  `yaml:"max_restarts"`
 }
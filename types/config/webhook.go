@@ -1,11 +1,87 @@
 package config
 
+import "github.com/adhocore/gronx"
+
+type WebhookMode string
+
+const (
+	WEBHOOK_MODE_EVENT   WebhookMode = "event"
+	WEBHOOK_MODE_SUMMARY WebhookMode = "summary"
+	WEBHOOK_MODE_BOTH    WebhookMode = "both"
+	WEBHOOK_MODE_DIGEST  WebhookMode = "digest"
+)
+
+const (
+	DIGEST_DAILY  string = "daily"
+	DIGEST_WEEKLY string = "weekly"
+)
+
+// webhooks keyed by instance name
 type Webhooks map[string]Webhook
 
 type Webhook struct {
-	Url    string        `yaml:"url"`
-	Author WebhookAuthor `yaml:"author"`
-	Kind   WebhookKind   `yaml:"kind"`
+	// service of the webhook, defaults to the instance name
+	Type      string           `yaml:"type"`
+	Url       string           `yaml:"url"`
+	Mode      WebhookMode      `yaml:"mode"`
+	Digest    string           `yaml:"digest"`
+	Author    WebhookAuthor    `yaml:"author"`
+	Kind      WebhookKind      `yaml:"kind"`
+	Templates WebhookTemplates `yaml:"templates"`
+	// slack and mattermost channel, optionally overridden per event type
+	Channel  string            `yaml:"channel"`
+	Channels map[string]string `yaml:"channels"`
+	// telegram bot token, chat and thread, optionally overridden per event type
+	Token    string                 `yaml:"token"`
+	ChatId   string                 `yaml:"chat_id"`
+	ThreadId int                    `yaml:"thread_id"`
+	Chats    map[string]WebhookChat `yaml:"chats"`
+	Format   string                 `yaml:"format"`
+	// ntfy topic, the token is used as access token
+	Topic string `yaml:"topic"`
+	// smtp server, sender and recipients, optionally overridden per event type
+	Host       string              `yaml:"host"`
+	Port       int                 `yaml:"port"`
+	Security   string              `yaml:"security"`
+	Username   string              `yaml:"username"`
+	Password   string              `yaml:"password"`
+	From       string              `yaml:"from"`
+	To         []string            `yaml:"to"`
+	Recipients map[string][]string `yaml:"recipients"`
+	// matrix room, the token is used as access token
+	Room     string   `yaml:"room"`
+	Notice   bool     `yaml:"notice"`
+	Mentions []string `yaml:"mentions"`
+}
+
+// cron expression of when digests are sent, daily and weekly are sent at 8:00
+func (w Webhook) DigestSchedule() string {
+	switch w.Digest {
+	case "", DIGEST_DAILY:
+		return "0 8 * * *"
+	case DIGEST_WEEKLY:
+		return "0 8 * * 1"
+	}
+	return w.Digest
+}
+
+// empty mode is allowed and means event
+func (w Webhook) IsModeValid() bool {
+	switch w.Mode {
+	case "", WEBHOOK_MODE_EVENT, WEBHOOK_MODE_SUMMARY, WEBHOOK_MODE_BOTH, WEBHOOK_MODE_DIGEST:
+		return true
+	}
+	return false
+}
+
+func (w Webhook) IsDigestValid() bool {
+	gron := gronx.New()
+	return gron.IsValid(w.DigestSchedule())
+}
+
+type WebhookChat struct {
+	ChatId   string `yaml:"chat_id"`
+	ThreadId int    `yaml:"thread_id"`
 }
 
 type WebhookAuthor struct {
@@ -18,4 +94,23 @@ type WebhookKind struct {
 	ImageSuccess     *bool `yaml:"image_success"`
 	ContainerSuccess *bool `yaml:"container_success"`
 	Errors           *bool `yaml:"errors"`
+	Pending          *bool `yaml:"pending"`
+}
+
+// templates keyed by event type, unset slots fall back to the default templates
+type WebhookTemplates map[string]WebhookTemplate
+
+type WebhookTemplate struct {
+	Title       string                 `yaml:"title"`
+	Description string                 `yaml:"description"`
+	File        string                 `yaml:"file"`
+	Url         string                 `yaml:"url"`
+	Color       int                    `yaml:"color"`
+	Fields      []WebhookTemplateField `yaml:"fields"`
+}
+
+type WebhookTemplateField struct {
+	Name   string `yaml:"name"`
+	Value  string `yaml:"value"`
+	Inline bool   `yaml:"inline"`
 }
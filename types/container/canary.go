@@ -0,0 +1,102 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// splits containers into groups using the same image and target digest, keeping the original order
+func (c Containers) GroupByImage() []Containers {
+	groups := []Containers{}
+	indexes := map[string]int{}
+
+	for _, container := range c {
+		key := container.Repository.String() + "@" + container.RemoteDigest.String()
+
+		if i, ok := indexes[key]; ok {
+			groups[i] = append(groups[i], container)
+		} else {
+			indexes[key] = len(groups)
+			groups = append(groups, Containers{container})
+		}
+	}
+	return groups
+}
+
+// returns the container labelled as canary, or the first one if none are
+func (c Containers) Canary() *Container {
+	for _, container := range c {
+		if val, ok := container.Labels[LABEL_CANARY]; ok {
+			if bval, err := strconv.ParseBool(val); err == nil && bval {
+				return container
+			}
+		}
+	}
+	return c[0]
+}
+
+func (c Containers) Without(container *Container) Containers {
+	result := Containers{}
+	for _, value := range c {
+		if value != container {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func (c Containers) Names() []string {
+	names := []string{}
+	for _, container := range c {
+		names = append(names, container.CleanName())
+	}
+	return names
+}
+
+// watches the container for the given duration and returns an error as soon as it stops, becomes unhealthy or restarts too often
+func (c *Container) Soak(ctx context.Context, client *client.Client, duration time.Duration, maxRestarts int) error {
+	logger := c.logger(ctx)
+	logger.Debug().Dur("duration", duration).Msg("Watching container")
+
+	initial, err := client.ContainerInspect(context.Background(), c.ID)
+	if err != nil {
+		logger.Err(err).Msg("ContainerInspect request failed")
+		return fmt.Errorf("inspecting container %s failed: %w", c.Name, err)
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	// Recheck container status every 10 seconds
+	ticker := time.NewTicker(time.Second * 10)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return nil
+		case <-ticker.C:
+			data, err := client.ContainerInspect(context.Background(), c.ID)
+			if err != nil {
+				logger.Err(err).Msg("ContainerInspect request failed")
+				return fmt.Errorf("inspecting container %s failed: %w", c.Name, err)
+			}
+
+			if restarts := data.RestartCount - initial.RestartCount; restarts > maxRestarts {
+				return fmt.Errorf("container %s restarted %d time(s)", c.Name, restarts)
+			}
+
+			if !data.State.Running && !data.State.Restarting {
+				return fmt.Errorf("container %s stopped with exit code %d", c.Name, data.State.ExitCode)
+			}
+
+			if data.State.Health != nil && data.State.Health.Status == types.Unhealthy {
+				return fmt.Errorf("container %s became unhealthy", c.Name)
+			}
+		}
+	}
+}
@@ -3,11 +3,24 @@ package container
 type DependencyType string
 
 const (
-	LABEL_ENABLE         string         = "yacu.enable"
-	LABEL_IMAGE_AGE      string         = "yacu.image_age"
-	LABEL_STOP_TIMEOUT   string         = "yacu.stop_timeout"
-	LABEL_DEPENDS_ON     string         = "com.docker.compose.depends_on"
-	DEPENDENCY_STARTED   DependencyType = "service_started"
-	DEPENDENCY_COMPLETED DependencyType = "service_completed_successfully"
-	DEPENDENCY_HEALTHY   DependencyType = "service_healthy"
+	LABEL_ENABLE           string         = "yacu.enable"
+	LABEL_IMAGE_AGE        string         = "yacu.image_age"
+	LABEL_AGE_SOURCE       string         = "yacu.age_source"
+	LABEL_STOP_TIMEOUT     string         = "yacu.stop_timeout"
+	LABEL_APPROVAL         string         = "yacu.approval"
+	LABEL_CANARY           string         = "yacu.canary"
+	LABEL_VERIFY_HTTP      string         = "yacu.verify.http"
+	LABEL_VERIFY_TCP       string         = "yacu.verify.tcp"
+	LABEL_VERIFY_EXEC      string         = "yacu.verify.exec"
+	LABEL_VERIFY_TIMEOUT   string         = "yacu.verify.timeout"
+	LABEL_HOOK_PRE_UPDATE  string         = "yacu.hook.pre_update"
+	LABEL_HOOK_POST_UPDATE string         = "yacu.hook.post_update"
+	LABEL_HOOK_TIMEOUT     string         = "yacu.hook.timeout"
+	LABEL_BACKUP           string         = "yacu.backup"
+	LABEL_NOTIFY           string         = "yacu.notify"
+	LABEL_COMPOSE_PROJECT  string         = "com.docker.compose.project"
+	LABEL_DEPENDS_ON       string         = "com.docker.compose.depends_on"
+	DEPENDENCY_STARTED     DependencyType = "service_started"
+	DEPENDENCY_COMPLETED   DependencyType = "service_completed_successfully"
+	DEPENDENCY_HEALTHY     DependencyType = "service_healthy"
 )
@@ -29,6 +29,9 @@ type Container struct {
 	Repository  reference.NamedTagged
 	StopTimeout int
 	MinImageAge int
+
+	// digest of the remote image this container should be updated to, set once an update is found
+	RemoteDigest digest.Digest
 }
 
 type Containers []*Container
@@ -135,7 +138,43 @@ func (c *Container) ShouldScan(all, stopped bool) bool {
 	return all
 }
 
-func (c *Container) IsOutdated() (bool, error) {
+func (c *Container) RequiresApproval(enabled bool) bool {
+	if val, ok := c.Labels[LABEL_APPROVAL]; ok {
+		if bval, err := strconv.ParseBool(val); err == nil {
+			return bval
+		}
+	}
+	return enabled
+}
+
+// returns the label value if set, validating it is left to the caller
+func (c *Container) AgeSource(source string) string {
+	if val, ok := c.Labels[LABEL_AGE_SOURCE]; ok && len(val) > 0 {
+		return strings.ToLower(strings.TrimSpace(val))
+	}
+	return source
+}
+
+func (c *Container) ShouldBackup(enabled bool) bool {
+	if val, ok := c.Labels[LABEL_BACKUP]; ok {
+		if bval, err := strconv.ParseBool(val); err == nil {
+			return bval
+		}
+	}
+	return enabled
+}
+
+// container name without the leading slash
+func (c *Container) CleanName() string {
+	return strings.TrimPrefix(c.Name, "/")
+}
+
+// ignoreCreated skips the check when the image age is not judged by its created time
+func (c *Container) IsOutdated(ignoreCreated bool) (bool, error) {
+	if ignoreCreated {
+		return true, nil
+	}
+
 	// parse created time from container image
 	createdTime := c.Image.Created
 
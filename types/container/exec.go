@@ -0,0 +1,81 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/rs/zerolog"
+)
+
+type ExecResult struct {
+	Command  string
+	ExitCode int
+	Output   string
+	Duration time.Duration
+}
+
+// runs a shell command inside the container and captures its combined output
+func Exec(ctx context.Context, client *client.Client, containerId, command string, timeout time.Duration) (*ExecResult, error) {
+	logger := zerolog.Ctx(ctx).With().Str("command", command).Logger()
+	started := time.Now()
+
+	execCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	created, err := client.ContainerExecCreate(
+		execCtx,
+		containerId,
+		types.ExecConfig{
+			AttachStdout: true,
+			AttachStderr: true,
+			Cmd:          []string{"/bin/sh", "-c", command},
+		},
+	)
+	if err != nil {
+		logger.Err(err).Msg("ContainerExecCreate request failed")
+		return nil, fmt.Errorf("creating exec instance failed: %w", err)
+	}
+
+	response, err := client.ContainerExecAttach(execCtx, created.ID, types.ExecStartCheck{})
+	if err != nil {
+		logger.Err(err).Msg("ContainerExecAttach request failed")
+		return nil, fmt.Errorf("attaching to exec instance failed: %w", err)
+	}
+	defer response.Close()
+
+	// the hijacked connection does not follow the context so it has to be closed manually
+	go func() {
+		<-execCtx.Done()
+		response.Close()
+	}()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, response.Reader); err != nil {
+		if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+			logger.Warn().Dur("timeout", timeout).Msg("Command timed out")
+			return &ExecResult{Command: command, ExitCode: -1, Output: output.String(), Duration: time.Since(started)},
+				fmt.Errorf("command %q timed out after %s", command, timeout)
+		}
+		logger.Err(err).Msg("Reading command output failed")
+		return nil, fmt.Errorf("reading command output failed: %w", err)
+	}
+
+	inspect, err := client.ContainerExecInspect(context.Background(), created.ID)
+	if err != nil {
+		logger.Err(err).Msg("ContainerExecInspect request failed")
+		return nil, fmt.Errorf("inspecting exec instance failed: %w", err)
+	}
+
+	return &ExecResult{
+		Command:  command,
+		ExitCode: inspect.ExitCode,
+		Output:   output.String(),
+		Duration: time.Since(started),
+	}, nil
+}
@@ -0,0 +1,78 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/terrails/yacu/types/database"
+	"github.com/terrails/yacu/utils"
+)
+
+type HookType string
+
+const (
+	HOOK_PRE_UPDATE  HookType = "pre_update"
+	HOOK_POST_UPDATE HookType = "post_update"
+)
+
+type HookResult struct {
+	Hook HookType
+	ExecResult
+}
+
+func (r *HookResult) Failed() bool {
+	return r.ExitCode != 0
+}
+
+func (r *HookResult) HookOutput() database.HookOutput {
+	return database.HookOutput{
+		Name:     string(r.Hook),
+		Command:  r.Command,
+		ExitCode: r.ExitCode,
+		Output:   r.ExecResult.Output,
+	}
+}
+
+// runs the command set by the hook label, returns nil if the container has no such hook
+func (c *Container) RunHook(ctx context.Context, client *client.Client, hook HookType, defaultTimeout time.Duration) (*HookResult, error) {
+	label := LABEL_HOOK_PRE_UPDATE
+	if hook == HOOK_POST_UPDATE {
+		label = LABEL_HOOK_POST_UPDATE
+	}
+
+	command := strings.TrimSpace(c.Labels[label])
+	if len(command) == 0 {
+		return nil, nil
+	}
+
+	timeout := defaultTimeout
+	if val, ok := c.Labels[LABEL_HOOK_TIMEOUT]; ok {
+		if duration, err := parseDuration(val); err == nil {
+			timeout = duration
+		}
+	}
+
+	logger := c.logger(ctx).With().Str("hook", string(hook)).Logger()
+	logger.Debug().Str("command", command).Msg("Running hook")
+
+	result, err := Exec(logger.WithContext(context.Background()), client, c.ID, command, timeout)
+	if result == nil {
+		return nil, err
+	}
+
+	hookResult := &HookResult{Hook: hook, ExecResult: *result}
+	if err != nil {
+		return hookResult, err
+	}
+
+	if hookResult.Failed() {
+		logger.Warn().Int("exit_code", result.ExitCode).Str("output", result.Output).Msg("Hook failed")
+		return hookResult, fmt.Errorf("%s hook %q exited with code %d: %s", hook, command, result.ExitCode, utils.TailString(strings.TrimSpace(result.Output), 1000))
+	}
+
+	logger.Debug().Dur("duration", result.Duration).Msg("Hook completed")
+	return hookResult, nil
+}
@@ -0,0 +1,220 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// amount of restarts during verification after which a container is considered to be crash looping
+const verifyMaxRestarts = 2
+
+type Probes struct {
+	Http    string
+	Tcp     string
+	Exec    string
+	Timeout time.Duration
+}
+
+// reads verification probes from labels, returns nil if none are set
+func (c *Container) Probes(defaultTimeout time.Duration) *Probes {
+	probes := Probes{
+		Http:    strings.TrimSpace(c.Labels[LABEL_VERIFY_HTTP]),
+		Tcp:     strings.TrimSpace(c.Labels[LABEL_VERIFY_TCP]),
+		Exec:    strings.TrimSpace(c.Labels[LABEL_VERIFY_EXEC]),
+		Timeout: defaultTimeout,
+	}
+
+	if len(probes.Http) == 0 && len(probes.Tcp) == 0 && len(probes.Exec) == 0 {
+		return nil
+	}
+
+	if val, ok := c.Labels[LABEL_VERIFY_TIMEOUT]; ok {
+		if duration, err := parseDuration(val); err == nil {
+			probes.Timeout = duration
+		}
+	}
+
+	return &probes
+}
+
+// waits until all probes pass, fails early if the container stops or is crash looping
+func (c *Container) Verify(ctx context.Context, client *client.Client, probes *Probes) error {
+	logger := c.logger(ctx)
+	logger.Debug().Dur("timeout", probes.Timeout).Msg("Verifying container")
+
+	timer := time.NewTimer(probes.Timeout)
+	defer timer.Stop()
+	// Recheck probes every 5 seconds
+	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+
+	var lastErr error = errors.New("no probe has completed yet")
+	for {
+		select {
+		case <-timer.C:
+			logger.Warn().Err(lastErr).Msg("Verification timed out")
+			return fmt.Errorf("verification timed out after %s: %w", probes.Timeout, lastErr)
+		case <-ticker.C:
+			data, err := client.ContainerInspect(context.Background(), c.ID)
+			if err != nil {
+				logger.Err(err).Msg("ContainerInspect request failed")
+				return fmt.Errorf("inspecting container %s failed: %w", c.Name, err)
+			}
+
+			if data.RestartCount > verifyMaxRestarts {
+				return fmt.Errorf("container %s is crash looping, restarted %d time(s)", c.Name, data.RestartCount)
+			}
+
+			if !data.State.Running {
+				if data.State.Restarting {
+					lastErr = fmt.Errorf("container %s is restarting", c.Name)
+					continue
+				}
+				return fmt.Errorf("container %s stopped with exit code %d", c.Name, data.State.ExitCode)
+			}
+
+			if lastErr = c.runProbes(ctx, client, &data, probes); lastErr == nil {
+				logger.Debug().Msg("Verification passed")
+				return nil
+			}
+			logger.Debug().Err(lastErr).Msg("Verification probe failed")
+		}
+	}
+}
+
+// watches the restart count of a container without probes, fails if it restarts or exits with an error.
+// Containers that exit with 0, e.g. one-shot and init containers, pass
+func (c *Container) WatchRestarts(ctx context.Context, client *client.Client, duration time.Duration) error {
+	logger := c.logger(ctx)
+	logger.Debug().Dur("duration", duration).Msg("Watching container restarts")
+
+	initial, err := client.ContainerInspect(context.Background(), c.ID)
+	if err != nil {
+		logger.Err(err).Msg("ContainerInspect request failed")
+		return fmt.Errorf("inspecting container %s failed: %w", c.Name, err)
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	// Recheck container status every 5 seconds
+	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+
+	for done := false; !done; {
+		select {
+		case <-timer.C:
+			done = true
+		case <-ticker.C:
+		}
+
+		data, err := client.ContainerInspect(context.Background(), c.ID)
+		if err != nil {
+			logger.Err(err).Msg("ContainerInspect request failed")
+			return fmt.Errorf("inspecting container %s failed: %w", c.Name, err)
+		}
+
+		if restarts := data.RestartCount - initial.RestartCount; restarts > 0 {
+			return fmt.Errorf("container %s is crash looping, restarted %d time(s)", c.Name, restarts)
+		}
+
+		if !data.State.Running {
+			if data.State.ExitCode != 0 {
+				return fmt.Errorf("container %s exited with code %d", c.Name, data.State.ExitCode)
+			}
+			if !data.State.Restarting {
+				logger.Debug().Msg("Container exited cleanly")
+				return nil
+			}
+		}
+	}
+
+	logger.Debug().Msg("Verification passed")
+	return nil
+}
+
+func (c *Container) runProbes(ctx context.Context, client *client.Client, data *types.ContainerJSON, probes *Probes) error {
+	host := containerAddress(data)
+
+	if len(probes.Tcp) > 0 {
+		address := net.JoinHostPort(host, probes.Tcp)
+		conn, err := net.DialTimeout("tcp", address, time.Second*5)
+		if err != nil {
+			return fmt.Errorf("tcp probe %s failed: %w", address, err)
+		}
+		conn.Close()
+	}
+
+	if len(probes.Http) > 0 {
+		probeUrl, err := url.Parse(probes.Http)
+		if err != nil {
+			return fmt.Errorf("invalid http probe %s: %w", probes.Http, err)
+		}
+
+		// localhost refers to the container itself, reach it through its address instead
+		if hostname := probeUrl.Hostname(); hostname == "localhost" || hostname == "127.0.0.1" {
+			if port := probeUrl.Port(); len(port) > 0 {
+				probeUrl.Host = net.JoinHostPort(host, port)
+			} else {
+				probeUrl.Host = host
+			}
+		}
+
+		httpClient := http.Client{Timeout: time.Second * 5}
+		response, err := httpClient.Get(probeUrl.String())
+		if err != nil {
+			return fmt.Errorf("http probe %s failed: %w", probeUrl, err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode >= 400 {
+			return fmt.Errorf("http probe %s failed with status %d", probeUrl, response.StatusCode)
+		}
+	}
+
+	if len(probes.Exec) > 0 {
+		result, err := Exec(ctx, client, c.ID, probes.Exec, time.Second*30)
+		if err != nil {
+			return fmt.Errorf("exec probe %q failed: %w", probes.Exec, err)
+		}
+		if result.ExitCode != 0 {
+			return fmt.Errorf("exec probe %q failed with exit code %d: %s", probes.Exec, result.ExitCode, strings.TrimSpace(result.Output))
+		}
+	}
+
+	return nil
+}
+
+// returns the address the container can be reached on from yacu
+func containerAddress(data *types.ContainerJSON) string {
+	if data.HostConfig != nil && data.HostConfig.NetworkMode.IsHost() {
+		return "127.0.0.1"
+	}
+
+	if data.NetworkSettings != nil {
+		for _, network := range data.NetworkSettings.Networks {
+			if network != nil && len(network.IPAddress) > 0 {
+				return network.IPAddress
+			}
+		}
+	}
+	return "127.0.0.1"
+}
+
+// accepts go durations (120s, 2m) as well as plain seconds
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if ival, err := strconv.ParseInt(value, 10, 0); err == nil {
+		return time.Duration(ival) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
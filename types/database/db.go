@@ -4,6 +4,9 @@ import (
 	"database/sql"
 )
 
+// fixed width so stored times compare correctly as text
+const sortableTime = "2006-01-02T15:04:05.000000000Z07:00"
+
 type Database struct {
 	DB *sql.DB
 }
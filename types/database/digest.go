@@ -0,0 +1,52 @@
+package database
+
+import (
+	"time"
+)
+
+type DigestSummaryRow struct {
+	RowId   int64     // unique id of row
+	Webhook string    // name of the webhook collecting the digest
+	Summary string    // run summary encoded as json
+	Created time.Time // time when the summary was stored
+}
+
+func (d Database) SaveDigestSummary(webhook string, summary string) error {
+	_, err := d.Exec(
+		"INSERT INTO digest_summaries (webhook, summary, created) VALUES (?, ?, ?)",
+		webhook, summary, time.Now().UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+// returns the summaries collected since the last digest, oldest first
+func (d Database) GetDigestSummaries(webhook string) ([]*DigestSummaryRow, error) {
+	rows, err := d.DB.Query("SELECT * FROM digest_summaries WHERE webhook=? ORDER BY id", webhook)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*DigestSummaryRow{}
+	for rows.Next() {
+		var entry DigestSummaryRow
+		var rcreated string
+
+		if err := rows.Scan(&entry.RowId, &entry.Webhook, &entry.Summary, &rcreated); err != nil {
+			return nil, err
+		}
+
+		if entry.Created, err = time.Parse(time.RFC3339Nano, rcreated); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+// removes the summaries included in a sent digest
+func (d Database) DeleteDigestSummaries(webhook string, lastId int64) error {
+	_, err := d.Exec("DELETE FROM digest_summaries WHERE webhook=? AND id<=?", webhook, lastId)
+	return err
+}
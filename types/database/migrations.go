@@ -0,0 +1,156 @@
+package database
+
+import "fmt"
+
+// each entry upgrades the schema by one version, tracked using sqlite's user_version pragma.
+// never modify an existing entry, append a new one instead.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS remote_images (
+		id 				INTEGER PRIMARY KEY,
+		name 			TEXT NOT NULL,
+		domain	 		TEXT NOT NULL,
+		created			TEXT NOT NULL,
+		digest			TEXT NOT NULL,
+		last_check      TEXT NOT NULL,
+		unique (name, domain)
+	);`,
+	`CREATE TABLE IF NOT EXISTS pending_updates (
+		id				INTEGER PRIMARY KEY,
+		token			TEXT NOT NULL UNIQUE,
+		container		TEXT NOT NULL,
+		image			TEXT NOT NULL,
+		digest			TEXT NOT NULL,
+		status			TEXT NOT NULL,
+		created			TEXT NOT NULL,
+		updated			TEXT NOT NULL,
+		unique (container, digest)
+	);`,
+	`CREATE TABLE IF NOT EXISTS update_history (
+		id				INTEGER PRIMARY KEY,
+		container		TEXT NOT NULL,
+		image			TEXT NOT NULL,
+		prev_digest		TEXT NOT NULL,
+		new_digest		TEXT NOT NULL,
+		status			TEXT NOT NULL,
+		message			TEXT NOT NULL,
+		hooks			TEXT NOT NULL,
+		started			TEXT NOT NULL,
+		finished		TEXT NOT NULL
+	);`,
+	`ALTER TABLE update_history ADD COLUMN backup TEXT NOT NULL DEFAULT '';`,
+	`CREATE TABLE IF NOT EXISTS remote_image_digests (
+		id				INTEGER PRIMARY KEY,
+		name			TEXT NOT NULL,
+		domain			TEXT NOT NULL,
+		digest			TEXT NOT NULL,
+		first_seen		TEXT NOT NULL,
+		unique (name, domain, digest)
+	);`,
+	// the actual first sighting of already stored digests is unknown, last check is the safe choice
+	`INSERT OR IGNORE INTO remote_image_digests (name, domain, digest, first_seen)
+		SELECT name, domain, digest, last_check FROM remote_images;`,
+	`CREATE TABLE IF NOT EXISTS digest_summaries (
+		id				INTEGER PRIMARY KEY,
+		webhook			TEXT NOT NULL,
+		summary			TEXT NOT NULL,
+		created			TEXT NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS notification_state (
+		id				INTEGER PRIMARY KEY,
+		fingerprint		TEXT NOT NULL UNIQUE,
+		kind			TEXT NOT NULL,
+		subject			TEXT NOT NULL,
+		context			TEXT NOT NULL,
+		error			TEXT NOT NULL,
+		count			INTEGER NOT NULL,
+		first_seen		TEXT NOT NULL,
+		last_seen		TEXT NOT NULL,
+		last_sent		TEXT NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS notification_outbox (
+		id				INTEGER PRIMARY KEY,
+		webhook			TEXT NOT NULL,
+		kind			TEXT NOT NULL,
+		event			TEXT NOT NULL,
+		status			TEXT NOT NULL,
+		attempts		INTEGER NOT NULL,
+		next_attempt	TEXT NOT NULL,
+		last_error		TEXT NOT NULL,
+		created			TEXT NOT NULL
+	);`,
+	`CREATE TABLE IF NOT EXISTS update_failures (
+		id				INTEGER PRIMARY KEY,
+		container		TEXT NOT NULL UNIQUE,
+		image			TEXT NOT NULL,
+		digest			TEXT NOT NULL,
+		count			INTEGER NOT NULL,
+		last_error		TEXT NOT NULL,
+		quarantined		INTEGER NOT NULL,
+		first_failed	TEXT NOT NULL,
+		last_failed		TEXT NOT NULL
+	);`,
+	// remote images were keyed by familiar name only, the cache is simply filled again by the next checks
+	`DROP TABLE remote_images;`,
+	`CREATE TABLE IF NOT EXISTS remote_images (
+		id				INTEGER PRIMARY KEY,
+		reference		TEXT NOT NULL,
+		platform		TEXT NOT NULL,
+		domain			TEXT NOT NULL,
+		created			TEXT NOT NULL,
+		digest			TEXT NOT NULL,
+		last_check		TEXT NOT NULL,
+		unique (reference, platform)
+	);`,
+	`CREATE TABLE IF NOT EXISTS image_digests (
+		id				INTEGER PRIMARY KEY,
+		reference		TEXT NOT NULL,
+		digest			TEXT NOT NULL,
+		first_seen		TEXT NOT NULL,
+		last_seen		TEXT NOT NULL,
+		unique (reference, digest)
+	);`,
+	// first sightings are kept, familiar names are turned into normalized references
+	`INSERT OR IGNORE INTO image_digests (reference, digest, first_seen, last_seen)
+		SELECT
+			CASE
+				WHEN domain = 'docker.io' AND instr(name, '/') = 0 THEN 'docker.io/library/' || name
+				WHEN domain = 'docker.io' AND substr(name, 1, 10) != 'docker.io/' THEN 'docker.io/' || name
+				ELSE name
+			END,
+			digest, first_seen, first_seen
+		FROM remote_image_digests;`,
+	`DROP TABLE remote_image_digests;`,
+	`CREATE INDEX IF NOT EXISTS image_digests_history ON image_digests (reference, first_seen);`,
+	// latest failing event, its container and image route the resolved notification
+	`ALTER TABLE notification_state ADD COLUMN event TEXT NOT NULL DEFAULT '';`,
+}
+
+func (d Database) Migrate() error {
+	var version int
+	if err := d.DB.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("reading schema version failed: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := d.DB.Begin()
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrating schema to version %d failed: %w", i+1, err)
+		}
+
+		// pragma does not support placeholders
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("updating schema version to %d failed: %w", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"time"
+)
+
+type NotificationStateRow struct {
+	RowId       int64     // unique id of row
+	Fingerprint string    // identifies repeats of the same error
+	Kind        string    // event type of the error
+	Subject     string    // container or image the error is about, empty for general errors
+	Context     string    // what failed
+	Error       string    // latest error message
+	Count       int       // number of times the error occurred
+	FirstSeen   time.Time // time of the first occurrence
+	LastSeen    time.Time // time of the latest occurrence
+	LastSent    time.Time // time the error was last notified about
+	Event       string    // latest failing event encoded as json
+}
+
+func (d Database) GetNotificationState(fingerprint string) (*NotificationStateRow, error) {
+	row, err := d.QueryRow("SELECT * FROM notification_state WHERE fingerprint=?", fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return scanNotificationState(row)
+}
+
+func (d Database) GetNotificationStates() ([]*NotificationStateRow, error) {
+	rows, err := d.DB.Query("SELECT * FROM notification_state ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*NotificationStateRow{}
+	for rows.Next() {
+		entry, err := scanNotificationState(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (d Database) SaveNotificationState(entry *NotificationStateRow) error {
+	_, err := d.Exec(
+		`INSERT INTO notification_state (fingerprint, kind, subject, context, error, count, first_seen, last_seen, last_sent, event)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (fingerprint) DO UPDATE SET
+				error=excluded.error, count=excluded.count, last_seen=excluded.last_seen, last_sent=excluded.last_sent, event=excluded.event`,
+		entry.Fingerprint, entry.Kind, entry.Subject, entry.Context, entry.Error, entry.Count,
+		entry.FirstSeen.UTC().Format(time.RFC3339Nano), entry.LastSeen.UTC().Format(sortableTime), entry.LastSent.UTC().Format(time.RFC3339Nano),
+		entry.Event,
+	)
+	return err
+}
+
+func (d Database) DeleteNotificationState(rowid int64) error {
+	_, err := d.Exec("DELETE FROM notification_state WHERE id=?", rowid)
+	return err
+}
+
+func scanNotificationState(row scanner) (*NotificationStateRow, error) {
+	var entry NotificationStateRow
+	var rfirstseen, rlastseen, rlastsent string
+
+	if err := row.Scan(
+		&entry.RowId, &entry.Fingerprint, &entry.Kind, &entry.Subject, &entry.Context, &entry.Error,
+		&entry.Count, &rfirstseen, &rlastseen, &rlastsent, &entry.Event,
+	); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if entry.FirstSeen, err = time.Parse(time.RFC3339Nano, rfirstseen); err != nil {
+		return nil, err
+	}
+	if entry.LastSeen, err = time.Parse(time.RFC3339Nano, rlastseen); err != nil {
+		return nil, err
+	}
+	if entry.LastSent, err = time.Parse(time.RFC3339Nano, rlastsent); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"time"
+)
+
+type OutboxStatus string
+
+const (
+	OUTBOX_QUEUED OutboxStatus = "queued"
+	OUTBOX_FAILED OutboxStatus = "failed"
+)
+
+type OutboxRow struct {
+	RowId       int64        // unique id of row
+	Webhook     string       // name of the webhook the notification is for
+	Kind        string       // event type of the notification
+	Event       string       // event encoded as json
+	Status      OutboxStatus // queued until delivered, failed once out of attempts
+	Attempts    int          // number of failed delivery attempts
+	NextAttempt time.Time    // earliest time of the next delivery attempt
+	LastError   string       // error of the last failed attempt
+	Created     time.Time    // time when the notification was queued
+}
+
+func (d Database) SaveOutbox(webhook string, kind string, event string) error {
+	now := time.Now().UTC().Format(sortableTime)
+	_, err := d.Exec(
+		"INSERT INTO notification_outbox (webhook, kind, event, status, attempts, next_attempt, last_error, created) VALUES (?, ?, ?, ?, 0, ?, '', ?)",
+		webhook, kind, event, OUTBOX_QUEUED, now, now,
+	)
+	return err
+}
+
+// returns the queued notifications that are due, oldest first
+func (d Database) GetDueOutbox(now time.Time) ([]*OutboxRow, error) {
+	return d.getOutbox(
+		"SELECT * FROM notification_outbox WHERE status=? AND next_attempt<=? ORDER BY id",
+		OUTBOX_QUEUED, now.UTC().Format(sortableTime),
+	)
+}
+
+// returns the time of the earliest queued delivery attempt, nil if nothing is queued
+func (d Database) GetNextOutboxAttempt() (*time.Time, error) {
+	row, err := d.QueryRow("SELECT MIN(next_attempt) FROM notification_outbox WHERE status=?", OUTBOX_QUEUED)
+	if err != nil {
+		return nil, err
+	}
+
+	var rnext *string
+	if err := row.Scan(&rnext); err != nil || rnext == nil {
+		return nil, err
+	}
+
+	next, err := time.Parse(time.RFC3339Nano, *rnext)
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+func (d Database) GetOutbox() ([]*OutboxRow, error) {
+	return d.getOutbox("SELECT * FROM notification_outbox ORDER BY id")
+}
+
+func (d Database) UpdateOutboxAttempt(entry *OutboxRow) error {
+	_, err := d.Exec(
+		"UPDATE notification_outbox SET event=?, status=?, attempts=?, next_attempt=?, last_error=? WHERE id=?",
+		entry.Event, entry.Status, entry.Attempts, entry.NextAttempt.UTC().Format(sortableTime), entry.LastError, entry.RowId,
+	)
+	return err
+}
+
+// queues failed notifications again with fresh attempts, all of them if rowid is 0
+func (d Database) RetryOutbox(rowid int64) (int64, error) {
+	stmt := "UPDATE notification_outbox SET status=?, attempts=0, next_attempt=? WHERE status=?"
+	args := []any{OUTBOX_QUEUED, time.Now().UTC().Format(sortableTime), OUTBOX_FAILED}
+	if rowid != 0 {
+		stmt += " AND id=?"
+		args = append(args, rowid)
+	}
+
+	result, err := d.Exec(stmt, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (d Database) DeleteOutbox(rowid int64) error {
+	_, err := d.Exec("DELETE FROM notification_outbox WHERE id=?", rowid)
+	return err
+}
+
+func (d Database) getOutbox(stmt string, args ...any) ([]*OutboxRow, error) {
+	rows, err := d.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*OutboxRow{}
+	for rows.Next() {
+		var entry OutboxRow
+		var rnext, rcreated string
+
+		if err := rows.Scan(
+			&entry.RowId, &entry.Webhook, &entry.Kind, &entry.Event, &entry.Status,
+			&entry.Attempts, &rnext, &entry.LastError, &rcreated,
+		); err != nil {
+			return nil, err
+		}
+
+		if entry.NextAttempt, err = time.Parse(time.RFC3339Nano, rnext); err != nil {
+			return nil, err
+		}
+		if entry.Created, err = time.Parse(time.RFC3339Nano, rcreated); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+type PendingStatus string
+
+const (
+	PENDING_WAITING    PendingStatus = "pending"
+	PENDING_APPROVED   PendingStatus = "approved"
+	PENDING_REJECTED   PendingStatus = "rejected"
+	PENDING_APPLIED    PendingStatus = "applied"
+	PENDING_SUPERSEDED PendingStatus = "superseded"
+)
+
+type PendingUpdateRow struct {
+	RowId     int64         // rowid
+	Token     string        // approval token, used to approve or reject
+	Container string        // container name without leading slash
+	Image     string        // familiar image name including tag
+	Digest    digest.Digest // remote digest the approval is tied to
+	Status    PendingStatus // current state of the update
+	Created   time.Time     // time when the update was found
+	Updated   time.Time     // time of the last status change
+}
+
+func (d Database) GetPendingUpdateFromToken(token string) (*PendingUpdateRow, error) {
+	return d.getPendingUpdate("SELECT * FROM pending_updates WHERE token=?", token)
+}
+
+func (d Database) GetPendingUpdateFor(container string, digest digest.Digest) (*PendingUpdateRow, error) {
+	return d.getPendingUpdate("SELECT * FROM pending_updates WHERE container=? AND digest=?", container, digest.String())
+}
+
+func (d Database) GetPendingUpdates(status ...PendingStatus) ([]*PendingUpdateRow, error) {
+	rows, err := d.DB.Query("SELECT * FROM pending_updates ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	updates := []*PendingUpdateRow{}
+	for rows.Next() {
+		update, err := scanPendingUpdate(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(status) == 0 {
+			updates = append(updates, update)
+			continue
+		}
+		for _, s := range status {
+			if update.Status == s {
+				updates = append(updates, update)
+				break
+			}
+		}
+	}
+	return updates, rows.Err()
+}
+
+// creates a new pending update and supersedes any older unresolved ones for the same container
+func (d Database) SavePendingUpdate(container, image string, digest digest.Digest) (*PendingUpdateRow, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	rnow := now.Format(time.RFC3339Nano)
+
+	if _, err := d.Exec(
+		"UPDATE pending_updates SET status=?, updated=? WHERE container=? AND status IN (?, ?)",
+		PENDING_SUPERSEDED, rnow, container, PENDING_WAITING, PENDING_APPROVED,
+	); err != nil {
+		return nil, err
+	}
+
+	result, err := d.Exec(
+		`INSERT INTO pending_updates (token, container, image, digest, status, created, updated)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		token, container, image, digest.String(), PENDING_WAITING, rnow, rnow,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &PendingUpdateRow{
+		RowId:     id,
+		Token:     token,
+		Container: container,
+		Image:     image,
+		Digest:    digest,
+		Status:    PENDING_WAITING,
+		Created:   now,
+		Updated:   now,
+	}, nil
+}
+
+func (d Database) UpdatePendingStatus(rowid int64, status PendingStatus) error {
+	rupdated := time.Now().UTC().Format(time.RFC3339Nano)
+
+	_, err := d.Exec("UPDATE pending_updates SET status=?, updated=? WHERE id=?", status, rupdated, rowid)
+	return err
+}
+
+// marks an approved update as applied once the container runs the approved digest
+func (d Database) MarkPendingApplied(container string, digest digest.Digest) error {
+	rupdated := time.Now().UTC().Format(time.RFC3339Nano)
+
+	_, err := d.Exec(
+		"UPDATE pending_updates SET status=?, updated=? WHERE container=? AND digest=? AND status=?",
+		PENDING_APPLIED, rupdated, container, digest.String(), PENDING_APPROVED,
+	)
+	return err
+}
+
+func (d Database) getPendingUpdate(stmt string, args ...any) (*PendingUpdateRow, error) {
+	row, err := d.QueryRow(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPendingUpdate(row)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPendingUpdate(row scanner) (*PendingUpdateRow, error) {
+	var id int64
+	var token, container, image, rdigest, status, rcreated, rupdated string
+
+	if err := row.Scan(&id, &token, &container, &image, &rdigest, &status, &rcreated, &rupdated); err != nil {
+		return nil, err
+	}
+
+	digest, err := digest.Parse(rdigest)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := time.Parse(time.RFC3339Nano, rcreated)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := time.Parse(time.RFC3339Nano, rupdated)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PendingUpdateRow{
+		RowId:     id,
+		Token:     token,
+		Container: container,
+		Image:     image,
+		Digest:    digest,
+		Status:    PendingStatus(status),
+		Created:   created,
+		Updated:   updated,
+	}, nil
+}
+
+func newToken() (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -8,19 +8,28 @@ import (
 
 type RemoteImageRow struct {
 	RowId     int64         // rowid
-	Name      string        // name including tag, used for unique row
+	Reference string        // normalized name including tag, e.g. docker.io/library/nginx:latest
+	Platform  string        // platform the image data was fetched for, e.g. linux/arm64/v8
 	Domain    string        // registry domain
-	Created   time.Time     // created time
+	Created   time.Time     // created time, zero if the image does not set it
 	Digest    digest.Digest // remote digest
 	LastCheck time.Time     // last update check time
 }
 
+type ImageDigestRow struct {
+	RowId     int64         // rowid
+	Reference string        // normalized name including tag
+	Digest    digest.Digest // digest the tag pointed to
+	FirstSeen time.Time     // time the digest was first seen
+	LastSeen  time.Time     // time the digest was last seen
+}
+
 func (d Database) GetRemoteImageFromId(imageId int64) (*RemoteImageRow, error) {
 	return d.getRemoteImage("SELECT * FROM remote_images WHERE id=?", imageId)
 }
 
-func (d Database) GetRemoteImageFromName(imageName string) (*RemoteImageRow, error) {
-	return d.getRemoteImage("SELECT * FROM remote_images WHERE name=?", imageName)
+func (d Database) GetRemoteImage(reference string, platform string) (*RemoteImageRow, error) {
+	return d.getRemoteImage("SELECT * FROM remote_images WHERE reference=? AND platform=?", reference, platform)
 }
 
 func (d Database) getRemoteImage(stmt string, args ...any) (*RemoteImageRow, error) {
@@ -29,65 +38,55 @@ func (d Database) getRemoteImage(stmt string, args ...any) (*RemoteImageRow, err
 		return nil, err
 	}
 
-	var id int64
-	var name, domain, rcreated, rdigest, rlastcheck string
+	var entry RemoteImageRow
+	var rcreated, rdigest, rlastcheck string
 
-	err = row.Scan(&id, &name, &domain, &rcreated, &rdigest, &rlastcheck)
+	err = row.Scan(&entry.RowId, &entry.Reference, &entry.Platform, &entry.Domain, &rcreated, &rdigest, &rlastcheck)
 	if err != nil {
 		return nil, err
 	}
 
-	created, err := time.Parse(time.RFC3339Nano, rcreated)
-	if err != nil {
+	if entry.Created, err = time.Parse(time.RFC3339Nano, rcreated); err != nil {
 		return nil, err
 	}
-
-	digest, err := digest.Parse(rdigest)
-	if err != nil {
+	if entry.Digest, err = digest.Parse(rdigest); err != nil {
 		return nil, err
 	}
-
-	lastcheck, err := time.Parse(time.RFC3339Nano, rlastcheck)
-	if err != nil {
+	if entry.LastCheck, err = time.Parse(time.RFC3339Nano, rlastcheck); err != nil {
 		return nil, err
 	}
-
-	return &RemoteImageRow{
-		RowId:     id,
-		Name:      name,
-		Domain:    domain,
-		Created:   created,
-		Digest:    digest,
-		LastCheck: lastcheck,
-	}, nil
+	return &entry, nil
 }
 
-func (d Database) SaveRemoteImage(name string, domain string, created time.Time, digest digest.Digest) (*int64, error) {
+// stores the checked image data, an existing row of the reference and platform keeps its id
+func (d Database) SaveRemoteImage(reference string, platform string, domain string, created time.Time, digest digest.Digest) (*int64, error) {
 	rlastcheck := time.Now().UTC().Format(time.RFC3339Nano)
 	rcreated := created.UTC().Format(time.RFC3339Nano)
-	rdigest := digest.String()
 
-	result, err := d.Exec(
-		`INSERT OR REPLACE 
-			INTO remote_images (name, domain, created, digest, last_check) 
-			VALUES (?, ?, ?, ?, ?)`,
-		name, domain, rcreated, rdigest, rlastcheck,
+	row, err := d.QueryRow(
+		`INSERT INTO remote_images (reference, platform, domain, created, digest, last_check)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (reference, platform) DO UPDATE SET
+				created=excluded.created, digest=excluded.digest, last_check=excluded.last_check
+			RETURNING id`,
+		reference, platform, domain, rcreated, digest.String(), rlastcheck,
 	)
-
 	if err != nil {
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
+	var id int64
+	if err := row.Scan(&id); err != nil {
 		return nil, err
 	}
-
 	return &id, nil
 }
 
 func (d Database) UpdateRemoteImage(rowid int64, created *time.Time, digest *digest.Digest) error {
-	rcreated := created.UTC().Format(time.RFC3339Nano)
+	rcreated := time.Time{}.Format(time.RFC3339Nano)
+	if created != nil {
+		rcreated = created.UTC().Format(time.RFC3339Nano)
+	}
 	rdigest := digest.String()
 
 	_, err := d.Exec("UPDATE remote_images SET created=?, digest=? WHERE id=?", rcreated, rdigest, rowid)
@@ -100,3 +99,75 @@ func (d Database) UpdateRemoteImageCheck(rowid int64) error {
 	_, err := d.Exec("UPDATE remote_images SET last_check=? WHERE id=?", lastCheck, rowid)
 	return err
 }
+
+// records the digest if it has not been seen before and returns the time it was first seen
+func (d Database) GetDigestFirstSeen(reference string, digest digest.Digest) (*time.Time, error) {
+	rnow := time.Now().UTC().Format(sortableTime)
+
+	row, err := d.QueryRow(
+		`INSERT INTO image_digests (reference, digest, first_seen, last_seen)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (reference, digest) DO UPDATE SET first_seen=first_seen
+			RETURNING first_seen`,
+		reference, digest.String(), rnow, rnow,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var rfirstseen string
+	if err := row.Scan(&rfirstseen); err != nil {
+		return nil, err
+	}
+
+	firstSeen, err := time.Parse(time.RFC3339Nano, rfirstseen)
+	if err != nil {
+		return nil, err
+	}
+	return &firstSeen, nil
+}
+
+// records that the registry returned the digest for the reference just now
+func (d Database) SaveDigestSeen(reference string, digest digest.Digest) error {
+	rnow := time.Now().UTC().Format(sortableTime)
+
+	_, err := d.Exec(
+		`INSERT INTO image_digests (reference, digest, first_seen, last_seen)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (reference, digest) DO UPDATE SET last_seen=excluded.last_seen`,
+		reference, digest.String(), rnow, rnow,
+	)
+	return err
+}
+
+// returns every digest the reference pointed to, oldest first
+func (d Database) GetDigestHistory(reference string) ([]*ImageDigestRow, error) {
+	rows, err := d.DB.Query("SELECT * FROM image_digests WHERE reference=? ORDER BY first_seen", reference)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*ImageDigestRow{}
+	for rows.Next() {
+		var entry ImageDigestRow
+		var rdigest, rfirstseen, rlastseen string
+
+		if err := rows.Scan(&entry.RowId, &entry.Reference, &rdigest, &rfirstseen, &rlastseen); err != nil {
+			return nil, err
+		}
+
+		if entry.Digest, err = digest.Parse(rdigest); err != nil {
+			return nil, err
+		}
+		if entry.FirstSeen, err = time.Parse(time.RFC3339Nano, rfirstseen); err != nil {
+			return nil, err
+		}
+		if entry.LastSeen, err = time.Parse(time.RFC3339Nano, rlastseen); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+type UpdateFailureRow struct {
+	RowId       int64         // unique id of row
+	Container   string        // container name without leading slash
+	Image       string        // familiar image name including tag
+	Digest      digest.Digest // remote digest the failing update targets
+	Count       int           // consecutive failed updates to the digest
+	LastError   string        // error of the latest failed update
+	Quarantined bool          // updates to the digest are skipped
+	FirstFailed time.Time     // time of the first failed update to the digest
+	LastFailed  time.Time     // time of the latest failed update
+}
+
+func (d Database) GetUpdateFailure(container string) (*UpdateFailureRow, error) {
+	row, err := d.QueryRow("SELECT * FROM update_failures WHERE container=?", container)
+	if err != nil {
+		return nil, err
+	}
+	return scanUpdateFailure(row)
+}
+
+func (d Database) GetQuarantinedContainers() ([]*UpdateFailureRow, error) {
+	rows, err := d.DB.Query("SELECT * FROM update_failures WHERE quarantined=1 ORDER BY container")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*UpdateFailureRow{}
+	for rows.Next() {
+		entry, err := scanUpdateFailure(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (d Database) SaveUpdateFailure(entry *UpdateFailureRow) error {
+	_, err := d.Exec(
+		`INSERT INTO update_failures (container, image, digest, count, last_error, quarantined, first_failed, last_failed)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (container) DO UPDATE SET
+				image=excluded.image, digest=excluded.digest, count=excluded.count, last_error=excluded.last_error,
+				quarantined=excluded.quarantined, first_failed=excluded.first_failed, last_failed=excluded.last_failed`,
+		entry.Container, entry.Image, entry.Digest.String(), entry.Count, entry.LastError, entry.Quarantined,
+		entry.FirstFailed.UTC().Format(time.RFC3339Nano), entry.LastFailed.UTC().Format(time.RFC3339Nano),
+	)
+	return err
+}
+
+// forgets the failures of the container, returns whether there were any
+func (d Database) DeleteUpdateFailure(container string) (bool, error) {
+	result, err := d.Exec("DELETE FROM update_failures WHERE container=?", container)
+	if err != nil {
+		return false, err
+	}
+	count, err := result.RowsAffected()
+	return count > 0, err
+}
+
+func scanUpdateFailure(row scanner) (*UpdateFailureRow, error) {
+	var entry UpdateFailureRow
+	var rdigest, rfirstfailed, rlastfailed string
+
+	if err := row.Scan(
+		&entry.RowId, &entry.Container, &entry.Image, &rdigest, &entry.Count, &entry.LastError,
+		&entry.Quarantined, &rfirstfailed, &rlastfailed,
+	); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if entry.Digest, err = digest.Parse(rdigest); err != nil {
+		return nil, err
+	}
+	if entry.FirstFailed, err = time.Parse(time.RFC3339Nano, rfirstfailed); err != nil {
+		return nil, err
+	}
+	if entry.LastFailed, err = time.Parse(time.RFC3339Nano, rlastfailed); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
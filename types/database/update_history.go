@@ -0,0 +1,104 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type HistoryStatus string
+
+const (
+	HISTORY_SUCCESS HistoryStatus = "success"
+	HISTORY_FAILED  HistoryStatus = "failed"
+)
+
+type HookOutput struct {
+	Name     string `json:"name"`
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	Output   string `json:"output"`
+}
+
+type UpdateHistoryRow struct {
+	RowId      int64         // rowid
+	Container  string        // container name without leading slash
+	Image      string        // familiar image name including tag
+	PrevDigest string        // digest before the update
+	NewDigest  string        // digest the container was updated to
+	Status     HistoryStatus // outcome of the update
+	Message    string        // error that stopped the update
+	Hooks      []HookOutput  // output of executed hooks
+	Backup     string        // id of the volume backup taken before the update
+	Started    time.Time     // time when the update started
+	Finished   time.Time     // time when the update finished
+}
+
+func (d Database) SaveUpdateHistory(entry *UpdateHistoryRow) (*int64, error) {
+	hooks, err := json.Marshal(entry.Hooks)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := d.Exec(
+		`INSERT INTO update_history (container, image, prev_digest, new_digest, status, message, hooks, started, finished, backup)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Container, entry.Image, entry.PrevDigest, entry.NewDigest, entry.Status, entry.Message, string(hooks),
+		entry.Started.UTC().Format(time.RFC3339Nano), entry.Finished.UTC().Format(time.RFC3339Nano), entry.Backup,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// returns the latest entries first, for all containers if container is empty
+func (d Database) GetUpdateHistory(container string, limit int) ([]*UpdateHistoryRow, error) {
+	stmt := "SELECT * FROM update_history ORDER BY id DESC LIMIT ?"
+	args := []any{limit}
+	if len(container) > 0 {
+		stmt = "SELECT * FROM update_history WHERE container=? ORDER BY id DESC LIMIT ?"
+		args = []any{container, limit}
+	}
+
+	rows, err := d.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*UpdateHistoryRow{}
+	for rows.Next() {
+		var entry UpdateHistoryRow
+		var status, hooks, rstarted, rfinished string
+
+		if err := rows.Scan(
+			&entry.RowId, &entry.Container, &entry.Image, &entry.PrevDigest, &entry.NewDigest,
+			&status, &entry.Message, &hooks, &rstarted, &rfinished, &entry.Backup,
+		); err != nil {
+			return nil, err
+		}
+
+		entry.Status = HistoryStatus(status)
+
+		if err := json.Unmarshal([]byte(hooks), &entry.Hooks); err != nil {
+			return nil, err
+		}
+
+		if entry.Started, err = time.Parse(time.RFC3339Nano, rstarted); err != nil {
+			return nil, err
+		}
+
+		if entry.Finished, err = time.Parse(time.RFC3339Nano, rfinished); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
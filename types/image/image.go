@@ -1,6 +1,7 @@
 package image
 
 import (
+	"strings"
 	"time"
 
 	"github.com/docker/distribution/reference"
@@ -16,6 +17,7 @@ type ImageData struct {
 	Created    time.Time
 	Repository reference.NamedTagged
 	RepoDigest digest.Digest
+	Metadata   Metadata
 }
 
 func NewData(image *types.ImageInspect, repository reference.NamedTagged) (*ImageData, error) {
@@ -29,11 +31,26 @@ func NewData(image *types.ImageInspect, repository reference.NamedTagged) (*Imag
 		return nil, err
 	}
 
+	labels := map[string]string{}
+	if image.Config != nil && image.Config.Labels != nil {
+		labels = image.Config.Labels
+	}
+
 	return &ImageData{
 		Raw:        image,
 		ID:         image.ID,
 		Created:    createdTime,
 		Repository: repository,
 		RepoDigest: *digest,
+		Metadata:   NewMetadata(labels),
 	}, nil
 }
+
+// platform the local image was built for, e.g. linux/arm64/v8
+func (i *ImageData) Platform() string {
+	parts := []string{i.Raw.Os, i.Raw.Architecture}
+	if len(i.Raw.Variant) > 0 {
+		parts = append(parts, i.Raw.Variant)
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,114 @@
+package image
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	LABEL_OCI_VERSION  string = "org.opencontainers.image.version"
+	LABEL_OCI_REVISION string = "org.opencontainers.image.revision"
+	LABEL_OCI_SOURCE   string = "org.opencontainers.image.source"
+	LABEL_OCI_URL      string = "org.opencontainers.image.url"
+	LABEL_OCI_CREATED  string = "org.opencontainers.image.created"
+)
+
+// release information read from the OCI labels/annotations of an image config
+type Metadata struct {
+	Version  string     `json:"version,omitempty"`
+	Revision string     `json:"revision,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	Url      string     `json:"url,omitempty"`
+	Created  *time.Time `json:"created,omitempty"`
+}
+
+func NewMetadata(labels map[string]string) Metadata {
+	metadata := Metadata{
+		Version:  strings.TrimSpace(labels[LABEL_OCI_VERSION]),
+		Revision: strings.TrimSpace(labels[LABEL_OCI_REVISION]),
+		Source:   normalizeSourceUrl(labels[LABEL_OCI_SOURCE]),
+		Url:      strings.TrimSpace(labels[LABEL_OCI_URL]),
+	}
+
+	if val, ok := labels[LABEL_OCI_CREATED]; ok {
+		if created, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(val)); err == nil {
+			metadata.Created = &created
+		}
+	}
+
+	return metadata
+}
+
+// returns "1.4.2 → 1.5.0", only the new version if previous one is unknown or empty if nothing is known
+func (m Metadata) VersionChange(prev Metadata) string {
+	if len(m.Version) == 0 {
+		return ""
+	}
+	if len(prev.Version) == 0 || prev.Version == m.Version {
+		return m.Version
+	}
+	return fmt.Sprintf("%s → %s", prev.Version, m.Version)
+}
+
+// returns a link to the source repository, falls back to the documentation url
+func (m Metadata) SourceUrl() string {
+	if len(m.Source) > 0 {
+		return m.Source
+	}
+	return m.Url
+}
+
+// returns a link comparing both revisions if the source is hosted on a known forge (GitHub, GitLab, Gitea)
+func (m Metadata) CompareUrl(prev Metadata) string {
+	if len(prev.Revision) == 0 || len(m.Revision) == 0 || prev.Revision == m.Revision {
+		return ""
+	}
+
+	source := m.Source
+	if len(source) == 0 {
+		source = prev.Source
+	}
+
+	parsed, err := url.Parse(source)
+	if err != nil || len(parsed.Host) == 0 {
+		return ""
+	}
+
+	// compare links only make sense on the repository root, i.e. host/owner/repo
+	if len(strings.Split(strings.Trim(parsed.Path, "/"), "/")) < 2 {
+		return ""
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	base := strings.TrimSuffix(source, "/")
+
+	switch {
+	case host == "github.com":
+		return fmt.Sprintf("%s/compare/%s...%s", base, prev.Revision, m.Revision)
+	case host == "gitlab.com" || strings.Contains(host, "gitlab"):
+		return fmt.Sprintf("%s/-/compare/%s...%s", base, prev.Revision, m.Revision)
+	case host == "gitea.com" || host == "codeberg.org" || strings.Contains(host, "gitea") || strings.Contains(host, "forgejo"):
+		return fmt.Sprintf("%s/compare/%s...%s", base, prev.Revision, m.Revision)
+	}
+	return ""
+}
+
+// converts common git remote formats into a browsable https url
+func normalizeSourceUrl(source string) string {
+	source = strings.TrimSpace(source)
+	source = strings.TrimPrefix(source, "git+")
+
+	// git@github.com:owner/repo.git
+	if strings.HasPrefix(source, "git@") {
+		source = "https://" + strings.Replace(strings.TrimPrefix(source, "git@"), ":", "/", 1)
+	} else if strings.HasPrefix(source, "git://") {
+		source = "https://" + strings.TrimPrefix(source, "git://")
+	} else if strings.HasPrefix(source, "ssh://git@") {
+		source = "https://" + strings.TrimPrefix(source, "ssh://git@")
+	}
+
+	source = strings.TrimSuffix(source, "/")
+	return strings.TrimSuffix(source, ".git")
+}
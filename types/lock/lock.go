@@ -0,0 +1,46 @@
+package lock
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// exclusive lock held by one goroutine of one process at a time, every process opening the same file shares it
+type FileLock struct {
+	path  string
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path}
+}
+
+// blocks until the lock is acquired
+func (l *FileLock) Lock() error {
+	l.mutex.Lock()
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		l.mutex.Unlock()
+		return fmt.Errorf("opening lock file %s failed: %w", l.path, err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		l.mutex.Unlock()
+		return fmt.Errorf("locking %s failed: %w", l.path, err)
+	}
+
+	l.file = file
+	return nil
+}
+
+func (l *FileLock) Unlock() {
+	// closing the file releases the lock, also if the process dies while holding it
+	l.file.Close()
+	l.file = nil
+	l.mutex.Unlock()
+}
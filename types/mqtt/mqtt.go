@@ -0,0 +1,267 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/config"
+	"github.com/terrails/yacu/types/webhook"
+)
+
+const (
+	PAYLOAD_ONLINE  = "online"
+	PAYLOAD_OFFLINE = "offline"
+	PAYLOAD_INSTALL = "install"
+)
+
+// publishes container update state as home assistant update entities, run results and errors
+type Client struct {
+	config  config.Mqtt
+	client  paho.Client
+	install func(name string)
+
+	// object ids of containers with a discovery config on the broker, used to remove entities of deleted containers.
+	// retained configs are read back from the broker so entities published before a restart are known as well
+	published     map[string]bool
+	publishedLock *sync.Mutex
+	lock          *sync.Mutex
+}
+
+// update state of a scanned container
+type ContainerState struct {
+	Name             string `json:"-"`
+	Title            string `json:"title"`
+	InstalledVersion string `json:"installed_version"`
+	LatestVersion    string `json:"latest_version"`
+	ReleaseSummary   string `json:"release_summary,omitempty"`
+	ReleaseUrl       string `json:"release_url,omitempty"`
+	EntityPicture    string `json:"entity_picture,omitempty"`
+}
+
+type discoveryConfig struct {
+	Name              string          `json:"name"`
+	UniqueId          string          `json:"unique_id"`
+	ObjectId          string          `json:"object_id"`
+	StateTopic        string          `json:"state_topic"`
+	CommandTopic      string          `json:"command_topic"`
+	PayloadInstall    string          `json:"payload_install"`
+	AvailabilityTopic string          `json:"availability_topic"`
+	Device            discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+}
+
+// connects to the broker, install is called with the container name when an install is requested
+func Connect(ctx context.Context, mqtt config.Mqtt, install func(name string)) (*Client, error) {
+	logger := zerolog.Ctx(ctx).With().Str("service", "mqtt").Logger()
+
+	c := &Client{
+		config:        mqtt,
+		install:       install,
+		published:     map[string]bool{},
+		publishedLock: &sync.Mutex{},
+		lock:          &sync.Mutex{},
+	}
+
+	options := paho.NewClientOptions().
+		AddBroker(mqtt.Broker).
+		SetClientID(mqtt.ClientId).
+		SetUsername(mqtt.Username).
+		SetPassword(mqtt.Password).
+		SetAutoReconnect(true).
+		SetWill(c.topic("status"), PAYLOAD_OFFLINE, 1, true).
+		SetOnConnectHandler(func(client paho.Client) {
+			// subscriptions are lost on reconnects
+			client.Subscribe(c.topic("+", "install"), 1, c.handleInstall(logger))
+			client.Subscribe(fmt.Sprintf("%s/update/%s/+/config", c.config.DiscoveryPrefix, objectId(c.config.ClientId)), 1, c.handleDiscovery)
+			client.Publish(c.topic("status"), 1, true, PAYLOAD_ONLINE)
+			logger.Info().Str("broker", mqtt.Broker).Msg("MQTT connected")
+		}).
+		SetConnectionLostHandler(func(client paho.Client, err error) {
+			logger.Err(err).Msg("MQTT connection lost")
+		})
+
+	c.client = paho.NewClient(options)
+	token := c.client.Connect()
+	if !token.WaitTimeout(time.Second * 30) {
+		return nil, fmt.Errorf("connecting to %s timed out", mqtt.Broker)
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("connecting to %s failed: %w", mqtt.Broker, err)
+	}
+	return c, nil
+}
+
+func (c *Client) handleInstall(logger zerolog.Logger) paho.MessageHandler {
+	return func(client paho.Client, message paho.Message) {
+		if string(message.Payload()) != PAYLOAD_INSTALL {
+			return
+		}
+
+		// <topic>/<container>/install
+		parts := strings.Split(strings.TrimPrefix(message.Topic(), c.config.Topic+"/"), "/")
+		if len(parts) != 2 {
+			return
+		}
+
+		logger.Info().Str("container", parts[0]).Msg("Install requested by MQTT")
+		go c.install(parts[0])
+	}
+}
+
+// keeps track of the entities on the broker, an empty config means the entity was removed
+func (c *Client) handleDiscovery(client paho.Client, message paho.Message) {
+	// <prefix>/update/<client id>/<object id>/config
+	parts := strings.Split(message.Topic(), "/")
+	if len(parts) < 2 {
+		return
+	}
+	c.setPublished(parts[len(parts)-2], len(message.Payload()) > 0)
+}
+
+func (c *Client) isPublished(id string) bool {
+	c.publishedLock.Lock()
+	defer c.publishedLock.Unlock()
+	return c.published[id]
+}
+
+func (c *Client) setPublished(id string, published bool) {
+	c.publishedLock.Lock()
+	defer c.publishedLock.Unlock()
+	if published {
+		c.published[id] = true
+	} else {
+		delete(c.published, id)
+	}
+}
+
+// publishes discovery configs and states of scanned containers, entities of containers no longer scanned are removed
+func (c *Client) PublishContainers(ctx context.Context, states []ContainerState) {
+	logger := zerolog.Ctx(ctx)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	scanned := map[string]bool{}
+	for _, state := range states {
+		scanned[objectId(state.Name)] = true
+		if err := c.publishContainer(state); err != nil {
+			logger.Err(err).Str("container", state.Name).Msg("Publishing container state to MQTT failed")
+		}
+	}
+
+	c.publishedLock.Lock()
+	removed := []string{}
+	for id := range c.published {
+		if !scanned[id] {
+			removed = append(removed, id)
+		}
+	}
+	c.publishedLock.Unlock()
+
+	for _, id := range removed {
+		// an empty retained config removes the entity
+		if err := c.publish(c.discoveryTopic(id), true, ""); err != nil {
+			logger.Err(err).Str("container", id).Msg("Removing container entity from MQTT failed")
+			continue
+		}
+		c.setPublished(id, false)
+	}
+}
+
+// publishes the state of a single container, e.g. after it was updated
+func (c *Client) PublishContainer(state ContainerState) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.publishContainer(state)
+}
+
+func (c *Client) publishContainer(state ContainerState) error {
+	if !c.isPublished(objectId(state.Name)) {
+		id := fmt.Sprintf("yacu_%s", objectId(state.Name))
+		if err := c.publishJson(c.discoveryTopic(state.Name), true, discoveryConfig{
+			Name:              state.Name,
+			UniqueId:          id,
+			ObjectId:          id,
+			StateTopic:        c.topic(state.Name, "state"),
+			CommandTopic:      c.topic(state.Name, "install"),
+			PayloadInstall:    PAYLOAD_INSTALL,
+			AvailabilityTopic: c.topic("status"),
+			Device: discoveryDevice{
+				Identifiers:  []string{c.config.ClientId},
+				Name:         "YACU",
+				Manufacturer: "Terrails",
+			},
+		}); err != nil {
+			return err
+		}
+		c.setPublished(objectId(state.Name), true)
+	}
+
+	return c.publishJson(c.topic(state.Name, "state"), true, state)
+}
+
+func (c *Client) Name() string {
+	return "mqtt"
+}
+
+// run results and errors go to their own topics, other events are represented by the container states
+func (c *Client) Send(ctx context.Context, event *webhook.Event) error {
+	if event.Kind == webhook.EVENT_RUN_SUMMARY {
+		return c.publishJson(c.topic("runs"), true, event)
+	}
+	if event.IsFailure() {
+		return c.publishJson(c.topic("errors"), false, event)
+	}
+	return nil
+}
+
+func (c *Client) Disconnect() {
+	c.publish(c.topic("status"), true, PAYLOAD_OFFLINE)
+	c.client.Disconnect(1000)
+}
+
+func (c *Client) topic(parts ...string) string {
+	return strings.Join(append([]string{c.config.Topic}, parts...), "/")
+}
+
+func (c *Client) discoveryTopic(name string) string {
+	return fmt.Sprintf("%s/update/%s/%s/config", c.config.DiscoveryPrefix, objectId(c.config.ClientId), objectId(name))
+}
+
+// home assistant only allows letters, numbers, underscores and hyphens in ids
+func objectId(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func (c *Client) publishJson(topic string, retained bool, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return c.publish(topic, retained, data)
+}
+
+func (c *Client) publish(topic string, retained bool, payload any) error {
+	token := c.client.Publish(topic, 1, retained, payload)
+	if !token.WaitTimeout(time.Second * 10) {
+		return fmt.Errorf("publishing to %s timed out", topic)
+	}
+	return token.Error()
+}
@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/terrails/yacu/types/retry"
+)
+
+var ErrRegistrySkipped = errors.New("registry skipped after repeated failures")
+
+// stops contacting a registry for the rest of a run once its checks keep failing
+type Breaker struct {
+	threshold int
+	lock      sync.Mutex
+	failures  map[string]int // consecutive transient failures per registry domain
+}
+
+func NewBreaker(threshold int) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		failures:  map[string]int{},
+	}
+}
+
+// closes all breakers, called at the start of every run
+func (b *Breaker) Reset() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.failures = map[string]int{}
+}
+
+func (b *Breaker) Allow(domain string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.threshold > 0 && b.failures[domain] >= b.threshold {
+		return fmt.Errorf("%s: %w", domain, ErrRegistrySkipped)
+	}
+	return nil
+}
+
+// records the result of a registry call, returns true when the registry has just been skipped
+func (b *Breaker) Record(domain string, err error) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err == nil {
+		b.failures[domain] = 0
+		return false
+	}
+	// missing images or bad credentials say nothing about the registry being reachable
+	if !retry.IsTransient(err) {
+		return false
+	}
+
+	b.failures[domain] += 1
+	return b.threshold > 0 && b.failures[domain] == b.threshold
+}
@@ -3,6 +3,7 @@ package registry
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/containers/image/v5/docker"
@@ -23,7 +24,8 @@ type ImageData struct {
 	OS      string
 }
 
-func GetImageDataFromRegistry(ctx context.Context, entries *config.RegistryEntries, named reference.Named) (*ImageData, error) {
+// fetches the image data of the tag, the image config is the one of the given platform for multi-platform images
+func GetImageDataFromRegistry(ctx context.Context, entries *config.RegistryEntries, named reference.Named, platform string) (*ImageData, error) {
 	logger := zerolog.Ctx(ctx)
 
 	ref, err := docker.NewReference(named)
@@ -35,6 +37,16 @@ func GetImageDataFromRegistry(ctx context.Context, entries *config.RegistryEntri
 	domain := reference.Domain(named)
 	sysCtx := entries.GetSystemContextFor(domain)
 
+	// os/architecture/variant, parts left empty default to the ones yacu runs on
+	parts := strings.SplitN(platform, "/", 3)
+	sysCtx.OSChoice = parts[0]
+	if len(parts) > 1 {
+		sysCtx.ArchitectureChoice = parts[1]
+	}
+	if len(parts) > 2 {
+		sysCtx.VariantChoice = parts[2]
+	}
+
 	src, err := ref.NewImageSource(context.Background(), sysCtx)
 	if err != nil {
 		logger.Err(err).Msg("fetching image source failed")
@@ -42,28 +54,30 @@ func GetImageDataFromRegistry(ctx context.Context, entries *config.RegistryEntri
 	}
 	defer src.Close()
 
-	img, err := image.FromUnparsedImage(context.Background(), sysCtx, image.UnparsedInstance(src, nil))
+	// the manifest is fetched once, the digest is taken from it before it is parsed
+	unparsed := image.UnparsedInstance(src, nil)
+	rawManifest, _, err := unparsed.Manifest(context.Background())
 	if err != nil {
-		logger.Err(err).Msg("fetching image failed")
-		return nil, fmt.Errorf("fetching image failed: %w", err)
+		logger.Err(err).Msg("fetching image manifest failed")
+		return nil, fmt.Errorf("fetching image manifest failed: %w", err)
 	}
 
-	imgData, err := img.Inspect(context.Background())
+	digest, err := manifest.Digest(rawManifest)
 	if err != nil {
-		logger.Err(err).Msg("image inspect call failed")
-		return nil, fmt.Errorf("image inspect call failed: %w", err)
+		logger.Err(err).Msg("fetching image digest failed")
+		return nil, fmt.Errorf("fetching image digest failed: %w", err)
 	}
 
-	rawManifest, _, err := src.GetManifest(context.Background(), nil)
+	img, err := image.FromUnparsedImage(context.Background(), sysCtx, unparsed)
 	if err != nil {
-		logger.Err(err).Msg("fetching image manifest failed")
-		return nil, fmt.Errorf("fetching image manifest failed: %w", err)
+		logger.Err(err).Msg("fetching image failed")
+		return nil, fmt.Errorf("fetching image failed: %w", err)
 	}
 
-	digest, err := manifest.Digest(rawManifest)
+	imgData, err := img.Inspect(context.Background())
 	if err != nil {
-		logger.Err(err).Msg("fetching image digest failed")
-		return nil, fmt.Errorf("fetching image digest failed: %w", err)
+		logger.Err(err).Msg("image inspect call failed")
+		return nil, fmt.Errorf("image inspect call failed: %w", err)
 	}
 
 	parsedData := ImageData{
@@ -77,3 +91,20 @@ func GetImageDataFromRegistry(ctx context.Context, entries *config.RegistryEntri
 
 	return &parsedData, nil
 }
+
+// fetches only the digest of the tag with a manifest HEAD request, fails if the registry does not send one
+func GetRemoteDigest(ctx context.Context, entries *config.RegistryEntries, named reference.Named) (digest.Digest, error) {
+	ref, err := docker.NewReference(named)
+	if err != nil {
+		return "", fmt.Errorf("parsing image name failed: %w", err)
+	}
+
+	sysCtx := entries.GetSystemContextFor(reference.Domain(named))
+
+	digest, err := docker.GetDigest(context.Background(), sysCtx, ref)
+	if err != nil {
+		zerolog.Ctx(ctx).Debug().Err(err).Msg("fetching image digest with HEAD request failed")
+		return "", fmt.Errorf("fetching image digest failed: %w", err)
+	}
+	return digest, nil
+}
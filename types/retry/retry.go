@@ -0,0 +1,86 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/docker/distribution/registry/api/errcode"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/config"
+)
+
+// 5xx responses as reported by the registry client
+var serverError = regexp.MustCompile(`(status code from registry|unexpected HTTP status:) 5\d\d`)
+
+// runs the operation until it succeeds, fails permanently or runs out of attempts
+func Do(ctx context.Context, policy config.RetryPolicy, operation func() error) error {
+	_, err := Value(ctx, policy, func() (struct{}, error) {
+		return struct{}{}, operation()
+	})
+	return err
+}
+
+func Value[T any](ctx context.Context, policy config.RetryPolicy, operation func() (T, error)) (T, error) {
+	logger := zerolog.Ctx(ctx)
+
+	for attempt := 1; ; attempt++ {
+		value, err := operation()
+		if err == nil || attempt >= policy.Attempts || !IsTransient(err) {
+			return value, err
+		}
+
+		// half of the delay is random so that retries of parallel failures spread out
+		delay := policy.Backoff(attempt)
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+		logger.Debug().Err(err).Int("attempt", attempt).Str("retry_in", delay.String()).Msg("Transient failure, retrying")
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return value, err
+		}
+	}
+}
+
+// whether the error may go away by trying again, e.g. timeouts, dropped connections and server errors
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, docker.ErrTooManyRequests) {
+		return true
+	}
+
+	// docker daemon
+	if client.IsErrConnectionFailed(err) || errdefs.IsUnavailable(err) || errdefs.IsSystem(err) || errdefs.IsDeadline(err) {
+		return true
+	}
+
+	// registry
+	var codeErr errcode.Error
+	if errors.As(err, &codeErr) && (codeErr.Code == errcode.ErrorCodeTooManyRequests || codeErr.Code == errcode.ErrorCodeUnavailable) {
+		return true
+	}
+	return serverError.MatchString(err.Error())
+}
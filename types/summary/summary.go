@@ -0,0 +1,149 @@
+package summary
+
+import (
+	"time"
+)
+
+// stages of a run an item can fail in
+const (
+	STAGE_SCAN    = "scan"    // listing and inspecting containers
+	STAGE_CHECK   = "check"   // looking for a newer remote image
+	STAGE_PULL    = "pull"    // pulling the new image
+	STAGE_UPDATE  = "update"  // recreating the container
+	STAGE_CLEANUP = "cleanup" // removing unused images
+)
+
+type Item struct {
+	Name     string        // container name or image for image related items
+	Image    string        // familiar image name including tag
+	Stage    string        // stage a failed item failed in
+	Message  string        // error or reason
+	Warnings []string      // warnings received during update
+	Duration time.Duration // time taken by the update
+}
+
+// collects the outcome of a single run, used to send one notification instead of one per event
+type Summary struct {
+	Started  time.Time
+	Finished time.Time
+
+	Updated []Item
+	Pulled  []Item
+	Failed  []Item
+	Skipped []Item
+	Held    []Item
+	// checked without finding an update, not part of notifications
+	Checked []Item `json:"-"`
+
+	RemovedImages int
+	FreedSpace    int64 // bytes freed by removing unused images
+}
+
+func New() *Summary {
+	return &Summary{
+		Started: time.Now(),
+		Updated: []Item{},
+		Pulled:  []Item{},
+		Failed:  []Item{},
+		Skipped: []Item{},
+		Held:    []Item{},
+		Checked: []Item{},
+	}
+}
+
+func (s *Summary) AddUpdated(name, image string, duration time.Duration, warnings ...string) {
+	s.Updated = append(s.Updated, Item{Name: name, Image: image, Duration: duration, Warnings: warnings})
+}
+
+func (s *Summary) AddPulled(image string) {
+	s.Pulled = append(s.Pulled, Item{Name: image, Image: image})
+}
+
+func (s *Summary) AddFailed(stage, name, image, context string, err error) {
+	message := context
+	if err != nil {
+		message = context + ": " + err.Error()
+	}
+	s.Failed = append(s.Failed, Item{Name: name, Image: image, Stage: stage, Message: message})
+}
+
+func (s *Summary) AddSkipped(name, image, reason string) {
+	s.Skipped = append(s.Skipped, Item{Name: name, Image: image, Message: reason})
+}
+
+func (s *Summary) AddHeld(name, image, reason string) {
+	s.Held = append(s.Held, Item{Name: name, Image: image, Message: reason})
+}
+
+func (s *Summary) AddChecked(name, image string) {
+	s.Checked = append(s.Checked, Item{Name: name, Image: image})
+}
+
+func (s *Summary) AddRemovedImage(size int64) {
+	s.RemovedImages += 1
+	s.FreedSpace += size
+}
+
+func (s *Summary) Finish() {
+	s.Finished = time.Now()
+}
+
+func (s *Summary) Duration() time.Duration {
+	return s.Finished.Sub(s.Started)
+}
+
+// returns all warnings prefixed with the name of the item they belong to
+func (s *Summary) Warnings() []string {
+	warnings := []string{}
+	for _, item := range s.Updated {
+		for _, warning := range item.Warnings {
+			warnings = append(warnings, item.Name+": "+warning)
+		}
+	}
+	return warnings
+}
+
+// number of failed items per stage
+func (s *Summary) FailedStages() map[string]int {
+	stages := map[string]int{}
+	for _, item := range s.Failed {
+		stages[item.Stage] += 1
+	}
+	return stages
+}
+
+// names and images of the items that were checked or updated without errors
+func (s *Summary) Succeeded() map[string]bool {
+	subjects := map[string]bool{}
+	for _, items := range [][]Item{s.Checked, s.Updated} {
+		for _, item := range items {
+			subjects[item.Name] = true
+			subjects[item.Image] = true
+		}
+	}
+	return subjects
+}
+
+// false if nothing worth notifying about happened
+func (s *Summary) HasChanges() bool {
+	return len(s.Updated) > 0 || len(s.Pulled) > 0 || len(s.Failed) > 0 || len(s.Skipped) > 0 || len(s.Held) > 0
+}
+
+// combines summaries of several runs
+func (s *Summary) Merge(other *Summary) {
+	if other.Started.Before(s.Started) {
+		s.Started = other.Started
+	}
+	if other.Finished.After(s.Finished) {
+		s.Finished = other.Finished
+	}
+
+	s.Updated = append(s.Updated, other.Updated...)
+	s.Pulled = append(s.Pulled, other.Pulled...)
+	s.Failed = append(s.Failed, other.Failed...)
+	s.Skipped = append(s.Skipped, other.Skipped...)
+	s.Held = append(s.Held, other.Held...)
+
+	s.RemovedImages += other.RemovedImages
+	s.FreedSpace += other.FreedSpace
+}
@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/config"
+	"github.com/terrails/yacu/types/database"
+)
+
+// ids, digests and addresses differ between otherwise equal errors, other numbers such as status codes tell causes apart
+var errorVariables = regexp.MustCompile(strings.Join([]string{
+	`\[[0-9a-fA-F:.]+\](:\d+)?`,                              // ipv6 address with optional port
+	`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`,                       // ipv4 address with optional port
+	`\b[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}\b`, // uuid
+	`\b[0-9a-f]{12,}\b`,                                      // container and image ids, digests
+}, "|"))
+
+type ResolvedData struct {
+	Kind      EventKind `json:"kind"`
+	Subject   string    `json:"subject,omitempty"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+func (w *Webhooks) SetDeduplication(notifications config.Notifications) {
+	w.dedupPeriod = time.Duration(notifications.DedupPeriod) * time.Hour
+	w.resolved = notifications.Resolved
+}
+
+// records the error and decides whether it should be sent, repeats are only sent once per period as reminders
+func (w *Webhooks) deduplicate(ctx context.Context, event *Event) bool {
+	if w.dedupPeriod <= 0 || !event.IsFailure() || event.Kind == EVENT_RUN_SUMMARY {
+		return true
+	}
+	logger := zerolog.Ctx(ctx)
+
+	now := event.Time
+	fingerprint := event.fingerprint()
+	state, err := w.db.GetNotificationState(fingerprint)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Err(err).Msg("Fetching notification state from local database failed")
+			return true
+		}
+		state = &database.NotificationStateRow{
+			Fingerprint: fingerprint,
+			Kind:        string(event.Kind),
+			Subject:     event.subject(),
+			Context:     event.Context,
+			FirstSeen:   now,
+			LastSent:    now,
+		}
+	}
+
+	state.Count += 1
+	state.Error = event.Error
+	state.LastSeen = now
+	if data, err := json.Marshal(event); err == nil {
+		state.Event = string(data)
+	}
+
+	send := state.Count == 1 || now.Sub(state.LastSent) >= w.dedupPeriod
+	if send {
+		state.LastSent = now
+		if state.Count > 1 {
+			event.Repeats = state.Count
+			event.FirstSeen = &state.FirstSeen
+		}
+	}
+
+	if err := w.db.SaveNotificationState(state); err != nil {
+		logger.Err(err).Msg("Writing notification state to local database failed")
+	}
+	return send
+}
+
+// sends resolved notifications for errors that did not occur again since the given time,
+// errors about a container or image are only resolved once it was checked or updated successfully
+func (w *Webhooks) Resolve(ctx context.Context, since time.Time, succeeded map[string]bool) {
+	if w.dedupPeriod <= 0 {
+		return
+	}
+	logger := zerolog.Ctx(ctx)
+
+	states, err := w.db.GetNotificationStates()
+	if err != nil {
+		logger.Err(err).Msg("Fetching notification states from local database failed")
+		return
+	}
+
+	// errors of quarantined containers did not stop, their updates are just not attempted anymore
+	quarantined := map[string]bool{}
+	for _, state := range states {
+		if state.Kind == string(EVENT_CONTAINER_QUARANTINED) && !state.LastSeen.Before(since) {
+			quarantined[state.Subject] = true
+		}
+	}
+
+	for _, state := range states {
+		if !state.LastSeen.Before(since) || quarantined[state.Subject] {
+			continue
+		}
+		// e.g. skipped by an open circuit breaker
+		if len(state.Subject) > 0 && !succeeded[state.Subject] {
+			continue
+		}
+
+		if w.resolved {
+			event := newEvent(EVENT_RESOLVED)
+			// routes match the resolved error like the failing one
+			var failed Event
+			if err := json.Unmarshal([]byte(state.Event), &failed); err == nil {
+				event.Container = failed.Container
+				event.Image = failed.Image
+				event.Registry = failed.Registry
+			}
+			event.Context = state.Context
+			event.Error = state.Error
+			event.Resolved = &ResolvedData{
+				Kind:      EventKind(state.Kind),
+				Subject:   state.Subject,
+				Count:     state.Count,
+				FirstSeen: state.FirstSeen,
+			}
+			w.dispatch(ctx, event)
+		}
+
+		if err := w.db.DeleteNotificationState(state.RowId); err != nil {
+			logger.Err(err).Msg("Removing notification state from local database failed")
+		}
+	}
+}
+
+func (e *Event) fingerprint() string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{string(e.Kind), e.subject(), e.Context, errorClass(e.Error)}, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
+
+// first line of the error without the parts that vary between occurrences
+func errorClass(err string) string {
+	class := strings.SplitN(err, "\n", 2)[0]
+	return errorVariables.ReplaceAllString(class, "#")
+}
+
+func (e *Event) subject() string {
+	if e.Container != nil {
+		return e.Container.Name
+	}
+	if e.Image != nil {
+		return e.Image.Name
+	}
+	return ""
+}
@@ -0,0 +1,306 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/terrails/yacu/types/config"
+)
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		same bool
+	}{
+		{
+			name: "digests",
+			a:    "pulling image failed: manifest sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef" + " not found",
+			b:    "pulling image failed: manifest sha256:" + "fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210" + " not found",
+			same: true,
+		},
+		{
+			name: "container ids",
+			a:    "container 3f4e5d6c7b8a is not running",
+			b:    "container a8b7c6d5e4f3 is not running",
+			same: true,
+		},
+		{
+			name: "ipv4 addresses and ports",
+			a:    "read tcp 172.17.0.2:54321->104.18.124.25:443: i/o timeout",
+			b:    "read tcp 172.17.0.2:40112->104.18.125.25:443: i/o timeout",
+			same: true,
+		},
+		{
+			name: "ipv6 addresses",
+			a:    "dial tcp [2606:4700::6812:7c19]:443: connect: network is unreachable",
+			b:    "dial tcp [2606:4700::6812:7d19]:443: connect: network is unreachable",
+			same: true,
+		},
+		{
+			name: "uuids",
+			a:    "request 123e4567-e89b-12d3-a456-426614174000 failed",
+			b:    "request 9b2c1f4e-0d3a-4c5b-8e7f-1a2b3c4d5e6f failed",
+			same: true,
+		},
+		{
+			name: "only the first line",
+			a:    "registry unavailable\nretry 1",
+			b:    "registry unavailable\nretry 2",
+			same: true,
+		},
+		{
+			name: "status codes",
+			a:    "registry returned 503",
+			b:    "registry returned 401",
+			same: false,
+		},
+		{
+			name: "short numbers",
+			a:    "container restarted 3 time(s)",
+			b:    "container stopped with exit code 137",
+			same: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := errorClass(test.a), errorClass(test.b)
+			if (a == b) != test.same {
+				t.Errorf("errorClass(%q) = %q, errorClass(%q) = %q, want same = %v", test.a, a, test.b, b, test.same)
+			}
+		})
+	}
+}
+
+type testNotifier struct{}
+
+func (testNotifier) Name() string {
+	return "test"
+}
+
+func (testNotifier) Send(ctx context.Context, event *Event) error {
+	return nil
+}
+
+// handler with deduplication and resolved notifications backed by a fresh database
+func newTestWebhooks(t *testing.T, period time.Duration) *Webhooks {
+	t.Helper()
+
+	db, err := config.DatabaseConfig{Path: filepath.Join(t.TempDir(), "yacu.db")}.LoadDatabase(context.Background())
+	if err != nil {
+		t.Fatalf("loading database failed: %v", err)
+	}
+	t.Cleanup(func() { db.DB.Close() })
+
+	enabled := true
+	w := NewWebhookHandler(*db)
+	w.Append("test", testNotifier{}, &config.Webhook{
+		Kind: config.WebhookKind{ImageSuccess: &enabled, ContainerSuccess: &enabled, Errors: &enabled, Pending: &enabled},
+	})
+	w.dedupPeriod = period
+	w.resolved = true
+	return w
+}
+
+func newTestError(kind EventKind, subject, err string, at time.Time) *Event {
+	event := newEvent(kind)
+	event.Time = at
+	event.Context = "Unable to check for updates"
+	event.Error = err
+	switch kind {
+	case EVENT_IMAGE_ERROR:
+		event.Image = &ImageData{Name: subject}
+	case EVENT_ERROR:
+	default:
+		event.Container = &ContainerData{Name: subject, Labels: map[string]string{"com.docker.compose.project": "stack"}}
+	}
+	return event
+}
+
+func TestDeduplicate(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	type occurrence struct {
+		after   time.Duration // since the first occurrence
+		err     string
+		send    bool
+		repeats int // repeats of a sent reminder
+	}
+
+	tests := []struct {
+		name        string
+		period      time.Duration
+		occurrences []occurrence
+	}{
+		{
+			name:   "first occurrence is sent",
+			period: 6 * time.Hour,
+			occurrences: []occurrence{
+				{after: 0, err: "registry returned 503", send: true},
+			},
+		},
+		{
+			name:   "repeats within the period are suppressed",
+			period: 6 * time.Hour,
+			occurrences: []occurrence{
+				{after: 0, err: "registry returned 503", send: true},
+				{after: time.Hour, err: "registry returned 503", send: false},
+				{after: 5 * time.Hour, err: "registry returned 503", send: false},
+			},
+		},
+		{
+			name:   "still failing after the period sends a reminder",
+			period: 6 * time.Hour,
+			occurrences: []occurrence{
+				{after: 0, err: "registry returned 503", send: true},
+				{after: time.Hour, err: "registry returned 503", send: false},
+				{after: 6 * time.Hour, err: "registry returned 503", send: true, repeats: 3},
+				{after: 7 * time.Hour, err: "registry returned 503", send: false},
+				{after: 12 * time.Hour, err: "registry returned 503", send: true, repeats: 5},
+			},
+		},
+		{
+			name:   "varying ids are the same error",
+			period: 6 * time.Hour,
+			occurrences: []occurrence{
+				{after: 0, err: "container 3f4e5d6c7b8a is not running", send: true},
+				{after: time.Hour, err: "container a8b7c6d5e4f3 is not running", send: false},
+			},
+		},
+		{
+			name:   "other status codes are other errors",
+			period: 6 * time.Hour,
+			occurrences: []occurrence{
+				{after: 0, err: "registry returned 503", send: true},
+				{after: time.Hour, err: "registry returned 401", send: true},
+			},
+		},
+		{
+			name:   "disabled deduplication sends everything",
+			period: 0,
+			occurrences: []occurrence{
+				{after: 0, err: "registry returned 503", send: true},
+				{after: time.Hour, err: "registry returned 503", send: true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWebhooks(t, test.period)
+
+			for i, o := range test.occurrences {
+				event := newTestError(EVENT_IMAGE_ERROR, "nginx:latest", o.err, start.Add(o.after))
+				if send := w.deduplicate(context.Background(), event); send != o.send {
+					t.Fatalf("occurrence %d: deduplicate() = %v, want %v", i, send, o.send)
+				}
+				if event.Repeats != o.repeats {
+					t.Errorf("occurrence %d: repeats = %d, want %d", i, event.Repeats, o.repeats)
+				}
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	since := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	before, during := since.Add(-time.Hour), since.Add(time.Minute)
+
+	tests := []struct {
+		name      string
+		errors    []*Event
+		succeeded map[string]bool
+		want      []string // subjects of the resolved notifications
+	}{
+		{
+			name:      "error of a checked container is resolved",
+			errors:    []*Event{newTestError(EVENT_CONTAINER_ERROR, "web", "Verification failed", before)},
+			succeeded: map[string]bool{"web": true},
+			want:      []string{"web"},
+		},
+		{
+			name:      "error of a checked image is resolved",
+			errors:    []*Event{newTestError(EVENT_IMAGE_ERROR, "nginx:latest", "registry returned 503", before)},
+			succeeded: map[string]bool{"web": true, "nginx:latest": true},
+			want:      []string{"nginx:latest"},
+		},
+		{
+			name:      "error seen again in the run is kept",
+			errors:    []*Event{newTestError(EVENT_CONTAINER_ERROR, "web", "Verification failed", during)},
+			succeeded: map[string]bool{"web": true},
+			want:      []string{},
+		},
+		{
+			name:      "container skipped by the breaker is kept",
+			errors:    []*Event{newTestError(EVENT_IMAGE_ERROR, "nginx:latest", "registry returned 503", before)},
+			succeeded: map[string]bool{},
+			want:      []string{},
+		},
+		{
+			name:      "general error is resolved",
+			errors:    []*Event{newTestError(EVENT_ERROR, "", "listing containers failed", before)},
+			succeeded: map[string]bool{},
+			want:      []string{""},
+		},
+		{
+			name: "quarantined container is kept",
+			errors: []*Event{
+				newTestError(EVENT_CONTAINER_ERROR, "web", "Verification failed", before),
+				newTestError(EVENT_CONTAINER_QUARANTINED, "web", "Verification failed", during),
+			},
+			succeeded: map[string]bool{"web": true},
+			want:      []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := newTestWebhooks(t, 6*time.Hour)
+			ctx := context.Background()
+
+			for _, event := range test.errors {
+				w.deduplicate(ctx, event)
+			}
+			w.Resolve(ctx, since, test.succeeded)
+
+			rows, err := w.db.GetOutbox()
+			if err != nil {
+				t.Fatalf("fetching outbox failed: %v", err)
+			}
+
+			got := []string{}
+			for _, row := range rows {
+				var event Event
+				if err := json.Unmarshal([]byte(row.Event), &event); err != nil {
+					t.Fatalf("decoding event failed: %v", err)
+				}
+				if event.Kind != EVENT_RESOLVED {
+					t.Fatalf("event kind = %s, want %s", event.Kind, EVENT_RESOLVED)
+				}
+				got = append(got, event.Resolved.Subject)
+
+				// routes match the resolved event like the failing one
+				if subject := event.subject(); subject != event.Resolved.Subject {
+					t.Errorf("routing subject = %q, want %q", subject, event.Resolved.Subject)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("resolved = %v, want %v", got, test.want)
+			}
+
+			states, err := w.db.GetNotificationStates()
+			if err != nil {
+				t.Fatalf("fetching notification states failed: %v", err)
+			}
+			if remaining := len(test.errors) - len(test.want); len(states) != remaining {
+				t.Errorf("remaining states = %d, want %d", len(states), remaining)
+			}
+		})
+	}
+}
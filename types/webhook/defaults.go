@@ -0,0 +1,140 @@
+package webhook
+
+import "github.com/terrails/yacu/types/config"
+
+const (
+	COLOR_FAILURE = 12723739
+	COLOR_SUCCESS = 2597142
+	COLOR_IMAGE   = 881812
+	COLOR_PENDING = 15105570
+	COLOR_NEUTRAL = 9807270
+)
+
+const errorDescription = "**{{ .Context }}**\n```{{ .Error }}```"
+
+const repeatsField = "{{ if .Repeats }}for {{ .Repeats }} runs since {{ .FirstSeen.Local.Format \"2006-01-02 15:04\" }}{{ end }}"
+
+const containerUpdatedDescription = "{{ with .Warnings }}__Following errors occurred during update:__\n" +
+	"{{ range . }}* {{ . }}\n{{ end }}{{ end }}" +
+	"{{ range .Hooks }}**Hook {{ .Name }} (exit code {{ .ExitCode }})**\n" +
+	"```{{ tail 900 (trim .Output) | default \"no output\" }}```\n{{ end }}"
+
+const summaryDescription = "{{ with .Summary }}{{ len .Updated }} updated, {{ len .Failed }} failed, " +
+	"{{ len .Skipped }} skipped and {{ len .Held }} held in {{ humanizeDuration .Duration }}" +
+	"{{ if .RemovedImages }}\nRemoved {{ .RemovedImages }} unused image(s), freeing {{ humanizeBytes .FreedSpace }}{{ end }}{{ end }}"
+
+const digestDescription = "{{ with .Summary }}Since {{ .Started.Local.Format \"2006-01-02 15:04\" }}: " +
+	"{{ len .Updated }} updated, {{ len .Failed }} failed, {{ len .Skipped }} skipped and {{ len .Held }} held" +
+	"{{ if .RemovedImages }}\nRemoved {{ .RemovedImages }} unused image(s), freeing {{ humanizeBytes .FreedSpace }}{{ end }}{{ end }}"
+
+const summaryItems = "{{ range . }}* {{ .Name }} — {{ .Message }}\n{{ end }}"
+
+// templates used for every slot that is not configured
+var defaultTemplates = map[EventKind]config.WebhookTemplate{
+	EVENT_ERROR: {
+		Title:       "An error occurred during update",
+		Description: errorDescription,
+		Color:       COLOR_FAILURE,
+		Fields: []config.WebhookTemplateField{
+			{Name: "Still failing", Value: repeatsField},
+		},
+	},
+	EVENT_IMAGE_UPDATED: {
+		Title: "{{ .Image.Name }} ({{ shortId .Image.ID }}) has been updated",
+		Color: COLOR_IMAGE,
+		Fields: []config.WebhookTemplateField{
+			{Name: "Version", Value: "{{ versionChange .PrevImage .Image }}", Inline: true},
+			{Name: "Source", Value: "{{ .Image.Release.SourceUrl }}", Inline: true},
+			{Name: "Changes", Value: "{{ with compareUrl .PrevImage .Image }}[{{ shortRevision $.PrevImage.Release.Revision }}...{{ shortRevision $.Image.Release.Revision }}]({{ . }}){{ end }}"},
+			{Name: "Previous Digest", Value: "{{ encoded .PrevImage.Digest }}"},
+			{Name: "New Digest", Value: "{{ encoded .Image.Digest }}"},
+		},
+	},
+	EVENT_IMAGE_ERROR: {
+		Title:       "{{ .Image.Name }} ({{ shortId .Image.ID }}) threw an error during update",
+		Description: errorDescription,
+		Color:       COLOR_FAILURE,
+		Fields: []config.WebhookTemplateField{
+			{Name: "Still failing", Value: repeatsField},
+		},
+	},
+	EVENT_IMAGE_REMOVAL_FAILED: {
+		Title:       "{{ shortId .Image.ID }} threw an error during removal",
+		Description: "```{{ .Error }}```",
+		Color:       COLOR_FAILURE,
+		Fields: []config.WebhookTemplateField{
+			{Name: "Long ID", Value: "{{ .Image.ID }}"},
+			{Name: "Last Tag", Value: "{{ .Image.Name }}"},
+			{Name: "Still failing", Value: repeatsField},
+		},
+	},
+	EVENT_CONTAINER_UPDATED: {
+		Title:       "{{ .Container.Name }} ({{ .Container.Image.Name }}) has been updated",
+		Description: containerUpdatedDescription,
+		Url:         "{{ index .Container.Labels \"net.unraid.docker.webui\" }}",
+		Color:       COLOR_SUCCESS,
+		Fields: []config.WebhookTemplateField{
+			{Name: "Container Id", Value: "{{ shortId .Container.ID }}", Inline: true},
+			{Name: "Image Id", Value: "{{ shortId .Container.Image.ID }}", Inline: true},
+			{Name: "Version", Value: "{{ versionChange .PrevImage .Image }}", Inline: true},
+		},
+	},
+	EVENT_CONTAINER_ERROR: {
+		Title:       "{{ .Container.Name }} ({{ .Container.Image.Name }}) threw an error during update",
+		Description: errorDescription,
+		Color:       COLOR_FAILURE,
+		Fields: []config.WebhookTemplateField{
+			{Name: "Container Id", Value: "{{ shortId .Container.ID }}", Inline: true},
+			{Name: "Image Id", Value: "{{ shortId .Container.Image.ID }}", Inline: true},
+			{Name: "Still failing", Value: repeatsField},
+		},
+	},
+	EVENT_CONTAINER_QUARANTINED: {
+		Title: "{{ .Container.Name }} ({{ .Container.Image.Name }}) is quarantined",
+		Description: "**Update failed {{ .Quarantine.Failures }} times in a row, it is skipped until a new image is released or the quarantine is cleared " +
+			"with `yacu quarantine clear {{ .Container.Name }}`**\n```{{ .Error }}```",
+		Color: COLOR_FAILURE,
+		Fields: []config.WebhookTemplateField{
+			{Name: "Skipped Digest", Value: "{{ shortId .Quarantine.Digest }}", Inline: true},
+			{Name: "Failing Since", Value: "{{ .Quarantine.FirstFailed.Local.Format \"2006-01-02 15:04\" }}", Inline: true},
+			{Name: "Still quarantined", Value: repeatsField},
+		},
+	},
+	EVENT_UPDATE_PENDING: {
+		Title:       "{{ .Container.Name }} ({{ .Container.Image.Name }}) has an update waiting for approval",
+		Description: "Approve with `yacu approve {{ .Pending.Token }}` or reject with `yacu reject {{ .Pending.Token }}`",
+		Color:       COLOR_PENDING,
+		Fields: []config.WebhookTemplateField{
+			{Name: "Approval Id", Value: "{{ .Pending.Token }}", Inline: true},
+			{Name: "Current Digest", Value: "{{ encoded .Container.Image.Digest }}"},
+			{Name: "New Digest", Value: "{{ encoded .Pending.Digest }}"},
+		},
+	},
+	// without a color the run outcome decides it
+	EVENT_RUN_SUMMARY: {
+		Title:       "Update run completed",
+		Description: summaryDescription,
+		Fields:      summaryFields,
+	},
+	EVENT_RESOLVED: {
+		Title:       "{{ with .Resolved.Subject }}{{ . }}: {{ end }}{{ .Context }} resolved",
+		Description: "No longer failing after {{ .Resolved.Count }} run(s) since {{ .Resolved.FirstSeen.Local.Format \"2006-01-02 15:04\" }}",
+		Color:       COLOR_SUCCESS,
+		Fields: []config.WebhookTemplateField{
+			{Name: "Last error", Value: "{{ with .Error }}```{{ . }}```{{ end }}"},
+		},
+	},
+	EVENT_DIGEST: {
+		Title:       "Update digest",
+		Description: digestDescription,
+		Fields:      summaryFields,
+	},
+}
+
+var summaryFields = []config.WebhookTemplateField{
+	{Name: "Updated ({{ len .Summary.Updated }})", Value: "{{ range .Summary.Updated }}* {{ .Name }} ({{ .Image }}) in {{ humanizeDuration .Duration }}\n{{ end }}"},
+	{Name: "Failed ({{ len .Summary.Failed }})", Value: "{{ with .Summary.Failed }}" + summaryItems + "{{ end }}"},
+	{Name: "Skipped ({{ len .Summary.Skipped }})", Value: "{{ with .Summary.Skipped }}" + summaryItems + "{{ end }}"},
+	{Name: "Held ({{ len .Summary.Held }})", Value: "{{ with .Summary.Held }}" + summaryItems + "{{ end }}"},
+	{Name: "Warnings", Value: "{{ range .Summary.Warnings }}* {{ . }}\n{{ end }}"},
+}
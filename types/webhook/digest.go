@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/adhocore/gronx"
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/summary"
+)
+
+// stores the run summary for every webhook that sends digests
+func (w *Webhooks) collectDigests(ctx context.Context, run *summary.Summary) {
+	logger := zerolog.Ctx(ctx)
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		logger.Err(err).Msg("encoding run summary failed")
+		return
+	}
+
+	event := newEvent(EVENT_DIGEST)
+	event.Summary = run
+
+	for _, hook := range w.webhooks {
+		if len(hook.digest) == 0 || !w.routesTo(hook.name, event) {
+			continue
+		}
+		if err := w.db.SaveDigestSummary(hook.name, string(data)); err != nil {
+			logger.Err(err).Str("webhook", hook.name).Msg("storing run summary for digest failed")
+		}
+	}
+}
+
+// starts sending digests on their schedule in the background
+func (w *Webhooks) RunDigests(ctx context.Context) {
+	for _, hook := range w.webhooks {
+		if len(hook.digest) > 0 {
+			go w.runDigest(ctx, hook)
+		}
+	}
+}
+
+func (w *Webhooks) runDigest(ctx context.Context, hook webhook) {
+	logger := zerolog.Ctx(ctx).With().Str("webhook", hook.name).Logger()
+
+	for {
+		nextTime, err := gronx.NextTick(hook.digest, false)
+		if err != nil {
+			logger.Err(err).Msg("calculating next digest time failed")
+			return
+		}
+
+		time.Sleep(time.Until(nextTime))
+
+		if err := w.sendDigest(hook); err != nil {
+			logger.Err(err).Msg("sending digest failed")
+		}
+	}
+}
+
+// merges the summaries collected since the last digest and queues them, nothing is sent without changes
+func (w *Webhooks) sendDigest(hook webhook) error {
+	rows, err := w.db.GetDigestSummaries(hook.name)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	var digest *summary.Summary
+	for _, row := range rows {
+		var run summary.Summary
+		if err := json.Unmarshal([]byte(row.Summary), &run); err != nil {
+			return err
+		}
+
+		if digest == nil {
+			digest = &run
+		} else {
+			digest.Merge(&run)
+		}
+	}
+
+	if digest.HasChanges() {
+		event := newEvent(EVENT_DIGEST)
+		event.Summary = digest
+		event.Warnings = digest.Warnings()
+		if err := w.enqueue(hook, event); err != nil {
+			return err
+		}
+	}
+
+	return w.db.DeleteDigestSummaries(hook.name, rows[len(rows)-1].RowId)
+}
@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/terrails/yacu/types/container"
+	"github.com/terrails/yacu/types/database"
+	"github.com/terrails/yacu/types/image"
+	"github.com/terrails/yacu/types/summary"
+	"github.com/terrails/yacu/utils"
+)
+
+type EventKind string
+
+const (
+	EVENT_ERROR                 EventKind = "error"
+	EVENT_IMAGE_UPDATED         EventKind = "image_updated"
+	EVENT_IMAGE_ERROR           EventKind = "image_error"
+	EVENT_IMAGE_REMOVAL_FAILED  EventKind = "image_removal_failed"
+	EVENT_CONTAINER_UPDATED     EventKind = "container_updated"
+	EVENT_CONTAINER_ERROR       EventKind = "container_error"
+	EVENT_CONTAINER_QUARANTINED EventKind = "container_quarantined"
+	EVENT_UPDATE_PENDING        EventKind = "update_pending"
+	EVENT_RUN_SUMMARY           EventKind = "run_summary"
+	EVENT_DIGEST                EventKind = "digest"
+	EVENT_RESOLVED              EventKind = "resolved"
+)
+
+var eventKinds = []EventKind{
+	EVENT_ERROR,
+	EVENT_IMAGE_UPDATED,
+	EVENT_IMAGE_ERROR,
+	EVENT_IMAGE_REMOVAL_FAILED,
+	EVENT_CONTAINER_UPDATED,
+	EVENT_CONTAINER_ERROR,
+	EVENT_CONTAINER_QUARANTINED,
+	EVENT_UPDATE_PENDING,
+	EVENT_RUN_SUMMARY,
+	EVENT_DIGEST,
+	EVENT_RESOLVED,
+}
+
+func IsEventKind(kind string) bool {
+	for _, value := range eventKinds {
+		if string(value) == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// whether the event reports a failure
+func (e *Event) IsFailure() bool {
+	switch e.Kind {
+	case EVENT_ERROR, EVENT_IMAGE_ERROR, EVENT_IMAGE_REMOVAL_FAILED, EVENT_CONTAINER_ERROR, EVENT_CONTAINER_QUARANTINED:
+		return true
+	case EVENT_RUN_SUMMARY, EVENT_DIGEST:
+		return e.Summary != nil && len(e.Summary.Failed) > 0
+	}
+	return false
+}
+
+// error for failures, success for updates and info for everything else
+func (e *Event) Severity() string {
+	if e.IsFailure() {
+		return SEVERITY_ERROR
+	}
+	switch e.Kind {
+	case EVENT_IMAGE_UPDATED, EVENT_CONTAINER_UPDATED, EVENT_RESOLVED:
+		return SEVERITY_SUCCESS
+	}
+	return SEVERITY_INFO
+}
+
+// everything a notifier or template can know about an event, only the data relevant to the kind is set
+type Event struct {
+	Kind     EventKind `json:"kind"`
+	Time     time.Time `json:"time"`
+	Context  string    `json:"context,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Warnings []string  `json:"warnings,omitempty"`
+
+	// affected container, the recreated one for updates
+	Container     *ContainerData `json:"container,omitempty"`
+	PrevContainer *ContainerData `json:"prev_container,omitempty"`
+	// affected image, the pulled one for updates
+	Image     *ImageData `json:"image,omitempty"`
+	PrevImage *ImageData `json:"prev_image,omitempty"`
+	// registry domain of the image
+	Registry string `json:"registry,omitempty"`
+
+	Hooks      []database.HookOutput `json:"hooks,omitempty"`
+	Pending    *PendingData          `json:"pending,omitempty"`
+	Quarantine *QuarantineData       `json:"quarantine,omitempty"`
+	Summary    *summary.Summary      `json:"summary,omitempty"`
+
+	// number of times a repeated error occurred and since when, only set for reminders
+	Repeats   int           `json:"repeats,omitempty"`
+	FirstSeen *time.Time    `json:"first_seen,omitempty"`
+	Resolved  *ResolvedData `json:"resolved,omitempty"`
+
+	// number of message parts already delivered, kept by notifiers that split long messages so a retry continues after them
+	Part int `json:"part,omitempty"`
+}
+
+type ContainerData struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Image  *ImageData        `json:"image"`
+}
+
+type ImageData struct {
+	ID      string         `json:"id"`
+	Name    string         `json:"name"` // familiar name including tag
+	Digest  string         `json:"digest"`
+	Created time.Time      `json:"created"`
+	Release image.Metadata `json:"release"`
+	// registry web page of the repository if known
+	Page string `json:"page,omitempty"`
+}
+
+type PendingData struct {
+	Token  string `json:"token"`
+	Digest string `json:"digest"`
+}
+
+type QuarantineData struct {
+	Digest      string    `json:"digest"` // remote digest of the skipped update
+	Failures    int       `json:"failures"`
+	FirstFailed time.Time `json:"first_failed"`
+}
+
+func newEvent(kind EventKind) *Event {
+	return &Event{
+		Kind: kind,
+		Time: time.Now().UTC(),
+	}
+}
+
+func newContainerData(c *container.Container) *ContainerData {
+	return &ContainerData{
+		ID:     c.ID,
+		Name:   c.CleanName(),
+		Labels: c.Labels,
+		Image:  newImageData(c.Image),
+	}
+}
+
+func newImageData(i *image.ImageData) *ImageData {
+	return &ImageData{
+		ID:      i.ID,
+		Name:    utils.FamiliarTagged(i.Repository),
+		Digest:  i.RepoDigest.String(),
+		Created: i.Created,
+		Release: i.Metadata,
+		Page:    utils.RegistryPageUrl(i.Repository),
+	}
+}
+
+func registryOf(named reference.Named) string {
+	return reference.Domain(named)
+}
+
+// link to the changes of the update, the release notes or the source of the image
+func (e *Event) ChangelogUrl() string {
+	if e.Image == nil {
+		return ""
+	}
+	if e.PrevImage != nil {
+		if compare := e.Image.Release.CompareUrl(e.PrevImage.Release); len(compare) > 0 {
+			return compare
+		}
+	}
+	if len(e.Image.Release.Url) > 0 {
+		return e.Image.Release.Url
+	}
+	return e.Image.Release.SourceUrl()
+}
+
+// error message or empty if there is none
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
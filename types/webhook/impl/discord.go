@@ -2,21 +2,32 @@ package webhooks
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
 	"github.com/disgoorg/disgo/webhook"
 	"github.com/rs/zerolog"
 	"github.com/terrails/yacu/types/config"
-	"github.com/terrails/yacu/types/container"
-	"github.com/terrails/yacu/types/image"
-	"github.com/terrails/yacu/utils"
+	yacuhook "github.com/terrails/yacu/types/webhook"
+)
+
+// embed limits of the discord api
+const (
+	discordTitleLimit       = 256
+	discordDescriptionLimit = 4096
+	discordFieldNameLimit   = 256
+	discordFieldValueLimit  = 1024
 )
 
 type DiscordWebhook struct {
-	config *config.Webhook
-	client webhook.Client
+	config    *config.Webhook
+	client    webhook.Client
+	templates *yacuhook.Templates
 }
 
 func SetupDiscordWebhook(ctx context.Context, config *config.Webhook) (*DiscordWebhook, error) {
@@ -26,144 +37,82 @@ func SetupDiscordWebhook(ctx context.Context, config *config.Webhook) (*DiscordW
 		logger.Err(err).Msg("failed to send discord webhook")
 		return nil, err
 	}
+	templates, err := yacuhook.NewTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
 	w := DiscordWebhook{
-		config: config,
-		client: client,
+		config:    config,
+		client:    client,
+		templates: templates,
 	}
 	return &w, nil
 }
 
-func (hook *DiscordWebhook) Error(ctx context.Context, context string, err error) {
-	logger := zerolog.Ctx(ctx)
-
-	if _, err := hook.client.CreateEmbeds([]discord.Embed{
-		hook.getStartingEmbedBuilder().
-			SetTitle("An error occurred during update").
-			SetDescription(fmt.Sprintf("**%s**\n```%v```", context, err)).
-			SetColor(12723739).
-			Build(),
-	},
-	); err != nil {
-		logger.Err(err).Msg("Encountered an error while sending a Discord Webhook")
-	}
+func (hook *DiscordWebhook) Name() string {
+	return "discord"
 }
 
-func (hook *DiscordWebhook) ImageUpdated(ctx context.Context, prevImage, newImage *image.ImageData) {
-	logger := zerolog.Ctx(ctx)
-
-	familiarNameTagged := utils.FamiliarTagged(newImage.Repository)
-	shortId := utils.ShortId(newImage.ID)
-	prevDigest := prevImage.RepoDigest.Encoded()
-	newDigest := newImage.RepoDigest.Encoded()
-
-	if _, err := hook.client.CreateEmbeds([]discord.Embed{
-		hook.getStartingEmbedBuilder().
-			SetTitle(fmt.Sprintf("%s (%s) has been updated", familiarNameTagged, shortId)).
-			AddField("Previous Digest", prevDigest, false).
-			AddField("New Digest", newDigest, false).
-			SetColor(881812).
-			Build(),
-	},
-	); err != nil {
-		logger.Err(err).Msg("Encountered an error while sending a Discord Webhook")
+func (hook *DiscordWebhook) Send(ctx context.Context, event *yacuhook.Event) error {
+	message, err := hook.templates.Render(event)
+	if err != nil {
+		return err
 	}
-}
 
-func (hook *DiscordWebhook) ImageError(ctx context.Context, image *image.ImageData, context string, err error) {
-	logger := zerolog.Ctx(ctx)
+	embed := hook.getStartingEmbedBuilder(event.Time).
+		SetTitle(truncate(message.Title, discordTitleLimit)).
+		SetDescription(truncate(message.Description, discordDescriptionLimit)).
+		SetColor(message.Color)
 
-	familiarNameTagged := utils.FamiliarTagged(image.Repository)
-	shortId := utils.ShortId(image.ID)
-
-	if _, err := hook.client.CreateEmbeds([]discord.Embed{
-		hook.getStartingEmbedBuilder().
-			SetTitle(fmt.Sprintf("%s (%s) threw an error during update", familiarNameTagged, shortId)).
-			SetDescription(fmt.Sprintf("**%s**\n```%v```", context, err)).
-			SetColor(12723739).
-			Build(),
-	},
-	); err != nil {
-		logger.Err(err).Msg("Encountered an error while sending a Discord Webhook")
+	if len(message.Url) > 0 {
+		embed.SetURL(message.Url)
 	}
-}
 
-func (hook *DiscordWebhook) ImageRemovalFailed(ctx context.Context, image *image.ImageData, err error) {
-	logger := zerolog.Ctx(ctx)
-
-	familiarNameTagged := utils.FamiliarTagged(image.Repository)
-	shortId := utils.ShortId(image.ID)
-
-	if _, err := hook.client.CreateEmbeds([]discord.Embed{
-		hook.getStartingEmbedBuilder().
-			SetTitle(fmt.Sprintf("%s threw an error during removal", shortId)).
-			SetDescription(fmt.Sprintf("```%v```", err)).
-			AddField("Long ID", image.ID, false).
-			AddField("Last Tag", familiarNameTagged, false).
-			SetColor(12723739).
-			Build(),
-	},
-	); err != nil {
-		logger.Err(err).Msg("Encountered an error while sending a Discord Webhook")
+	for _, field := range message.Fields {
+		embed.AddField(truncate(field.Name, discordFieldNameLimit), truncateLines(field.Value, discordFieldValueLimit), field.Inline)
 	}
-}
 
-func (hook *DiscordWebhook) ContainerUpdated(ctx context.Context, prevContainer, newContainer *container.Container, warnings ...string) {
-	logger := zerolog.Ctx(ctx)
+	_, err = hook.client.CreateEmbeds([]discord.Embed{embed.Build()})
 
-	familiarNameTagged := utils.FamiliarTagged(newContainer.Repository)
-	containerShortId := utils.ShortId(newContainer.ID)
-	imageShortId := utils.ShortId(newContainer.Image.ID)
-
-	embed := hook.getStartingEmbedBuilder().
-		SetTitle(fmt.Sprintf("%s (%s) has been updated", newContainer.Name, familiarNameTagged)).
-		AddField("Container Id", containerShortId, true).
-		AddField("Image Id", imageShortId, true).
-		SetColor(2597142)
-
-	if webui, ok := newContainer.Labels["net.unraid.docker.webui"]; ok && len(webui) > 0 {
-		embed.SetURL(webui)
-	}
-
-	if len(warnings) > 0 {
-		description := "__Following errors occurred during update:\n"
-		for _, value := range warnings {
-			description += fmt.Sprintf("* %s\n", value)
-		}
-		embed.SetDescription(description)
+	// the client retries rate limited requests itself, afterwards the outbox waits as long as discord asks
+	var restErr rest.Error
+	if errors.As(err, &restErr) && restErr.Response != nil {
+		return withRetryAfter(restErr.Response, err)
 	}
+	return err
+}
 
-	if _, err := hook.client.CreateEmbeds([]discord.Embed{
-		embed.Build(),
-	},
-	); err != nil {
-		logger.Err(err).Msg("Encountered an error while sending a Discord Webhook")
+// limits are counted in characters, a multi-byte character is never split
+func truncate(value string, max int) string {
+	if utf8.RuneCountInString(value) <= max {
+		return value
 	}
+	return string([]rune(value)[:max-1]) + "…"
 }
 
-func (hook *DiscordWebhook) ContainerError(ctx context.Context, container *container.Container, context string, err error) {
-	logger := zerolog.Ctx(ctx)
+// keeps as many whole lines as fit into the limit
+func truncateLines(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
 
-	familiarNameTagged := utils.FamiliarTagged(container.Repository)
-	containerShortId := utils.ShortId(container.ID)
-	imageShortId := utils.ShortId(container.Image.ID)
-
-	if _, err := hook.client.CreateEmbeds([]discord.Embed{
-		hook.getStartingEmbedBuilder().
-			SetTitle(fmt.Sprintf("%s (%s) threw an error during update", container.Name, familiarNameTagged)).
-			SetDescription(fmt.Sprintf("**%s**\n```%v```", context, err)).
-			AddField("Container Id", containerShortId, true).
-			AddField("Image Id", imageShortId, true).
-			SetColor(12723739).
-			Build(),
-	},
-	); err != nil {
-		logger.Err(err).Msg("Encountered an error while sending a Discord Webhook")
+	lines := strings.Split(value, "\n")
+	result := ""
+	for i, line := range lines {
+		if len(result)+len(line)+1 > max-24 {
+			if len(result) == 0 {
+				return truncate(value, max)
+			}
+			return result + fmt.Sprintf("… and %d more", len(lines)-i)
+		}
+		result += line + "\n"
 	}
+	return result
 }
 
-func (hook *DiscordWebhook) getStartingEmbedBuilder() *discord.EmbedBuilder {
+func (hook *DiscordWebhook) getStartingEmbedBuilder(timestamp time.Time) *discord.EmbedBuilder {
 	builder := discord.NewEmbedBuilder()
-	builder.SetTimestamp(time.Now().UTC())
+	builder.SetTimestamp(timestamp)
 	builder.SetFooterText("YACU by Terrails")
 
 	if len(hook.config.Author.Name) != 0 {
@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/terrails/yacu/types/config"
+	yacuhook "github.com/terrails/yacu/types/webhook"
+)
+
+// posts the event data as json, for services without a dedicated notifier
+type GenericWebhook struct {
+	config    *config.Webhook
+	templates *yacuhook.Templates
+}
+
+type genericPayload struct {
+	*yacuhook.Event
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+func SetupGenericWebhook(ctx context.Context, config *config.Webhook) (*GenericWebhook, error) {
+	if len(config.Url) == 0 {
+		return nil, fmt.Errorf("generic webhook url is not set")
+	}
+
+	templates, err := yacuhook.NewTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+	return &GenericWebhook{
+		config:    config,
+		templates: templates,
+	}, nil
+}
+
+func (hook *GenericWebhook) Name() string {
+	return "generic"
+}
+
+func (hook *GenericWebhook) Send(ctx context.Context, event *yacuhook.Event) error {
+	message, err := hook.templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	body := *message
+	body.Title = ""
+
+	headers := map[string]string{}
+	if len(hook.config.Token) > 0 {
+		headers["Authorization"] = "Bearer " + hook.config.Token
+	}
+	return postJson(ctx, hook.config.Url, genericPayload{
+		Event:   event,
+		Title:   message.Title,
+		Message: body.Markdown(),
+	}, headers)
+}
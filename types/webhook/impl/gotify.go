@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/terrails/yacu/types/config"
+	yacuhook "github.com/terrails/yacu/types/webhook"
+)
+
+type GotifyWebhook struct {
+	config    *config.Webhook
+	templates *yacuhook.Templates
+}
+
+type gotifyMessage struct {
+	Title    string         `json:"title"`
+	Message  string         `json:"message"`
+	Priority int            `json:"priority"`
+	Extras   map[string]any `json:"extras"`
+}
+
+// gotify priorities range from 0 to 10, clients notify loudly from 8
+var gotifyPriorities = map[pushPriority]int{
+	PRIORITY_LOW:     2,
+	PRIORITY_DEFAULT: 5,
+	PRIORITY_HIGH:    8,
+}
+
+func SetupGotifyWebhook(ctx context.Context, config *config.Webhook) (*GotifyWebhook, error) {
+	if len(config.Url) == 0 {
+		return nil, fmt.Errorf("gotify server url is not set")
+	}
+	if len(config.Token) == 0 {
+		return nil, fmt.Errorf("gotify application token is not set")
+	}
+
+	templates, err := yacuhook.NewTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+	return &GotifyWebhook{
+		config:    config,
+		templates: templates,
+	}, nil
+}
+
+func (hook *GotifyWebhook) Name() string {
+	return "gotify"
+}
+
+func (hook *GotifyWebhook) Send(ctx context.Context, event *yacuhook.Event) error {
+	message, err := hook.templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	body := *message
+	body.Title = ""
+
+	extras := map[string]any{
+		"client::display": map[string]string{"contentType": "text/markdown"},
+	}
+	click := message.Url
+	if len(click) == 0 {
+		click = event.ChangelogUrl()
+	}
+	if len(click) > 0 {
+		extras["client::notification"] = map[string]any{"click": map[string]string{"url": click}}
+	}
+
+	return postJson(ctx, strings.TrimSuffix(hook.config.Url, "/")+"/message", gotifyMessage{
+		Title:    message.Title,
+		Message:  body.Markdown(),
+		Priority: gotifyPriorities[priorityOf(event)],
+		Extras:   extras,
+	}, map[string]string{"X-Gotify-Key": hook.config.Token})
+}
@@ -0,0 +1,78 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	yacuhook "github.com/terrails/yacu/types/webhook"
+)
+
+var httpClient = &http.Client{Timeout: time.Second * 30}
+
+// sends the body as json, a non 2xx response is returned as an error
+func postJson(ctx context.Context, url string, body any, headers map[string]string) error {
+	return sendJson(ctx, http.MethodPost, url, body, headers)
+}
+
+func sendJson(ctx context.Context, method string, url string, body any, headers map[string]string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("encoding request failed: %w", err)
+	}
+
+	headers["Content-Type"] = "application/json"
+	return send(ctx, method, url, bytes.NewReader(data), headers)
+}
+
+func send(ctx context.Context, method string, url string, body io.Reader, headers map[string]string) error {
+	request, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		err := fmt.Errorf("request failed with status %s: %s", response.Status, strings.TrimSpace(string(message)))
+		return withRetryAfter(response, err)
+	}
+	return nil
+}
+
+// wraps the error with the delay requested by a rate limited or unavailable service
+func withRetryAfter(response *http.Response, err error) error {
+	if response.StatusCode != http.StatusTooManyRequests && response.StatusCode != http.StatusServiceUnavailable {
+		return err
+	}
+	return &yacuhook.RetryAfterError{After: retryAfter(response.Header), Err: err}
+}
+
+// parses the Retry-After header given either in seconds or as a date, 0 if not set
+func retryAfter(header http.Header) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if len(value) == 0 {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package webhooks
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// the small markdown subset used by templates: code blocks, inline code, links, bold and underline
+var markdownToken = regexp.MustCompile("```\\n?([\\s\\S]*?)```|`([^`\\n]+)`|\\[([^\\]]+)\\]\\(([^)\\s]+)\\)|\\*\\*|__")
+
+type markdownFormat struct {
+	escape     func(string) string
+	escapeCode func(string) string
+	escapeUrl  func(string) string
+	codeBlock  string
+	code       string
+	link       string
+	bold       [2]string
+	underline  [2]string
+}
+
+var htmlFormat = markdownFormat{
+	escape:     html.EscapeString,
+	escapeCode: html.EscapeString,
+	escapeUrl:  html.EscapeString,
+	codeBlock:  "<pre>%s</pre>",
+	code:       "<code>%s</code>",
+	link:       "<a href=\"%[2]s\">%[1]s</a>",
+	bold:       [2]string{"<b>", "</b>"},
+	underline:  [2]string{"<u>", "</u>"},
+}
+
+var markdownV2Escaper = strings.NewReplacer(
+	"\\", "\\\\", "_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)", "~", "\\~", "`", "\\`",
+	">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=", "|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.", "!", "\\!",
+)
+
+var markdownV2CodeEscaper = strings.NewReplacer("\\", "\\\\", "`", "\\`")
+
+// telegram flavoured markdown
+var markdownV2Format = markdownFormat{
+	escape:     markdownV2Escaper.Replace,
+	escapeCode: markdownV2CodeEscaper.Replace,
+	escapeUrl:  strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace,
+	codeBlock:  "```\n%s```",
+	code:       "`%s`",
+	link:       "[%s](%s)",
+	bold:       [2]string{"*", "*"},
+	underline:  [2]string{"__", "__"},
+}
+
+func markdownToHtml(text string) string {
+	return convertMarkdown(text, htmlFormat)
+}
+
+func markdownToTelegram(text string) string {
+	return convertMarkdown(text, markdownV2Format)
+}
+
+func convertMarkdown(text string, format markdownFormat) string {
+	var builder strings.Builder
+	bold, underline := false, false
+
+	last := 0
+	for _, match := range markdownToken.FindAllStringSubmatchIndex(text, -1) {
+		builder.WriteString(format.escape(text[last:match[0]]))
+		last = match[1]
+
+		token := text[match[0]:match[1]]
+		switch {
+		case match[2] >= 0:
+			builder.WriteString(fmt.Sprintf(format.codeBlock, format.escapeCode(text[match[2]:match[3]])))
+		case match[4] >= 0:
+			builder.WriteString(fmt.Sprintf(format.code, format.escapeCode(text[match[4]:match[5]])))
+		case match[6] >= 0:
+			builder.WriteString(fmt.Sprintf(format.link, format.escape(text[match[6]:match[7]]), format.escapeUrl(text[match[8]:match[9]])))
+		case token == "**":
+			builder.WriteString(format.bold[boolIndex(bold)])
+			bold = !bold
+		case token == "__":
+			builder.WriteString(format.underline[boolIndex(underline)])
+			underline = !underline
+		}
+	}
+	builder.WriteString(format.escape(text[last:]))
+
+	// close what a truncated or split text left open
+	if underline {
+		builder.WriteString(format.underline[1])
+	}
+	if bold {
+		builder.WriteString(format.bold[1])
+	}
+	return builder.String()
+}
+
+func boolIndex(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// splits markdown on line boundaries into parts whose size after conversion is at most limit, code blocks are closed and reopened
+func splitMarkdown(text string, limit int, size func(string) int) []string {
+	parts := []string{}
+	current := ""
+	fenced := false
+
+	for _, line := range strings.Split(text, "\n") {
+		// leave room for closing and reopening a code block
+		for excess := size(line) - (limit - 8); excess > 0; excess = size(line) - (limit - 8) {
+			line = truncate(line, max(utf8.RuneCountInString(line)-excess, 1))
+		}
+
+		candidate := line
+		if len(current) > 0 {
+			candidate = current + "\n" + line
+		}
+		if size(candidate)+4 > limit {
+			parts, current = appendPart(parts, current, fenced)
+		}
+		if len(current) > 0 {
+			current += "\n"
+		}
+		current += line
+
+		fenced = fenced != (strings.Count(line, "```")%2 == 1)
+	}
+	if len(strings.TrimSpace(current)) > 0 {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+func appendPart(parts []string, current string, fenced bool) ([]string, string) {
+	if len(strings.TrimSpace(current)) == 0 {
+		return parts, ""
+	}
+	if fenced {
+		return append(parts, current+"```"), "```"
+	}
+	return append(parts, current), ""
+}
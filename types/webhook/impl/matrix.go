@@ -0,0 +1,132 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/terrails/yacu/types/config"
+	yacuhook "github.com/terrails/yacu/types/webhook"
+)
+
+type MatrixWebhook struct {
+	config      *config.Webhook
+	templates   *yacuhook.Templates
+	url         string
+	transaction atomic.Int64
+}
+
+type matrixMessage struct {
+	MsgType       string          `json:"msgtype"`
+	Body          string          `json:"body"`
+	Format        string          `json:"format"`
+	FormattedBody string          `json:"formatted_body"`
+	Mentions      *matrixMentions `json:"m.mentions,omitempty"`
+}
+
+type matrixMentions struct {
+	UserIds []string `json:"user_ids"`
+}
+
+func SetupMatrixWebhook(ctx context.Context, config *config.Webhook) (*MatrixWebhook, error) {
+	if len(config.Url) == 0 {
+		return nil, fmt.Errorf("matrix homeserver url is not set")
+	}
+	if len(config.Token) == 0 {
+		return nil, fmt.Errorf("matrix access token is not set")
+	}
+	if len(config.Room) == 0 {
+		return nil, fmt.Errorf("matrix room is not set")
+	}
+
+	templates, err := yacuhook.NewTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &MatrixWebhook{
+		config:    config,
+		templates: templates,
+		url:       fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/", strings.TrimSuffix(config.Url, "/"), url.PathEscape(config.Room)),
+	}
+	// transaction ids have to be unique for the access token, also across restarts
+	hook.transaction.Store(time.Now().UnixMilli())
+	return hook, nil
+}
+
+func (hook *MatrixWebhook) Name() string {
+	return "matrix"
+}
+
+func (hook *MatrixWebhook) Send(ctx context.Context, event *yacuhook.Event) error {
+	message, err := hook.templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	content := matrixMessage{
+		MsgType:       "m.text",
+		Body:          message.Markdown(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: matrixHtml(message),
+	}
+	if hook.config.Notice {
+		content.MsgType = "m.notice"
+	}
+
+	// failures mention the configured users, which notifies them even for notices
+	if event.IsFailure() && len(hook.config.Mentions) > 0 {
+		pills := []string{}
+		for _, user := range hook.config.Mentions {
+			pills = append(pills, fmt.Sprintf("<a href=\"https://matrix.to/#/%s\">%s</a>", html.EscapeString(user), html.EscapeString(user)))
+		}
+		content.Body = strings.Join(hook.config.Mentions, " ") + "\n" + content.Body
+		content.FormattedBody = strings.Join(pills, " ") + "<br>" + content.FormattedBody
+		content.Mentions = &matrixMentions{UserIds: hook.config.Mentions}
+	}
+
+	transaction := fmt.Sprintf("yacu-%d", hook.transaction.Add(1))
+	return sendJson(ctx, http.MethodPut, hook.url+transaction, content, map[string]string{
+		"Authorization": "Bearer " + hook.config.Token,
+	})
+}
+
+func matrixHtml(message *yacuhook.Message) string {
+	var builder strings.Builder
+
+	title := html.EscapeString(message.Title)
+	if len(message.Url) > 0 {
+		title = fmt.Sprintf("<a href=\"%s\">%s</a>", html.EscapeString(message.Url), title)
+	}
+	builder.WriteString(fmt.Sprintf("<strong>%s</strong>", title))
+
+	if len(message.Description) > 0 {
+		builder.WriteString("<br>")
+		builder.WriteString(newlinesToBreaks(markdownToHtml(message.Description)))
+	}
+	for _, field := range message.Fields {
+		builder.WriteString(fmt.Sprintf("<br><strong>%s</strong>: %s", markdownToHtml(field.Name), newlinesToBreaks(markdownToHtml(field.Value))))
+	}
+	return builder.String()
+}
+
+// keeps line breaks outside of preformatted blocks
+func newlinesToBreaks(text string) string {
+	parts := strings.Split(text, "<pre>")
+	for i, part := range parts {
+		if i == 0 {
+			parts[i] = strings.ReplaceAll(part, "\n", "<br>")
+			continue
+		}
+		code, rest, found := strings.Cut(part, "</pre>")
+		if found {
+			parts[i] = code + "</pre>" + strings.ReplaceAll(rest, "\n", "<br>")
+		}
+	}
+	return strings.Join(parts, "<pre>")
+}
@@ -0,0 +1,113 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/terrails/yacu/types/config"
+	yacuhook "github.com/terrails/yacu/types/webhook"
+)
+
+type NtfyWebhook struct {
+	config    *config.Webhook
+	templates *yacuhook.Templates
+}
+
+type ntfyMessage struct {
+	Topic    string       `json:"topic"`
+	Title    string       `json:"title"`
+	Message  string       `json:"message"`
+	Priority int          `json:"priority"`
+	Tags     []string     `json:"tags"`
+	Markdown bool         `json:"markdown"`
+	Click    string       `json:"click,omitempty"`
+	Actions  []ntfyAction `json:"actions,omitempty"`
+}
+
+type ntfyAction struct {
+	Action string `json:"action"`
+	Label  string `json:"label"`
+	Url    string `json:"url"`
+}
+
+// ntfy priorities range from 1 (min) to 5 (max)
+var ntfyPriorities = map[pushPriority]int{
+	PRIORITY_LOW:     2,
+	PRIORITY_DEFAULT: 3,
+	PRIORITY_HIGH:    4,
+}
+
+func SetupNtfyWebhook(ctx context.Context, config *config.Webhook) (*NtfyWebhook, error) {
+	if len(config.Url) == 0 {
+		return nil, fmt.Errorf("ntfy server url is not set")
+	}
+	if len(config.Topic) == 0 {
+		return nil, fmt.Errorf("ntfy topic is not set")
+	}
+
+	templates, err := yacuhook.NewTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+	return &NtfyWebhook{
+		config:    config,
+		templates: templates,
+	}, nil
+}
+
+func (hook *NtfyWebhook) Name() string {
+	return "ntfy"
+}
+
+func (hook *NtfyWebhook) Send(ctx context.Context, event *yacuhook.Event) error {
+	message, err := hook.templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	body := *message
+	body.Title = ""
+
+	request := ntfyMessage{
+		Topic:    hook.config.Topic,
+		Title:    message.Title,
+		Message:  body.Markdown(),
+		Priority: ntfyPriorities[priorityOf(event)],
+		Tags:     ntfyTags(event),
+		Markdown: true,
+		Click:    message.Url,
+	}
+
+	if changelog := event.ChangelogUrl(); len(changelog) > 0 {
+		request.Actions = append(request.Actions, ntfyAction{Action: "view", Label: "Changelog", Url: changelog})
+	}
+	if event.Image != nil && len(event.Image.Page) > 0 {
+		request.Actions = append(request.Actions, ntfyAction{Action: "view", Label: "Registry", Url: event.Image.Page})
+	}
+	if len(request.Click) == 0 && len(request.Actions) > 0 {
+		request.Click = request.Actions[0].Url
+	}
+
+	headers := map[string]string{}
+	if len(hook.config.Token) > 0 {
+		headers["Authorization"] = "Bearer " + hook.config.Token
+	}
+	return postJson(ctx, strings.TrimSuffix(hook.config.Url, "/"), request, headers)
+}
+
+// emoji shortcodes shown in front of the title followed by the event type
+func ntfyTags(event *yacuhook.Event) []string {
+	tag := "information_source"
+	switch {
+	case event.IsFailure():
+		tag = "rotating_light"
+	case event.Kind == yacuhook.EVENT_UPDATE_PENDING:
+		tag = "hourglass"
+	case event.Kind == yacuhook.EVENT_RUN_SUMMARY:
+		tag = "clipboard"
+	case event.Kind == yacuhook.EVENT_IMAGE_UPDATED, event.Kind == yacuhook.EVENT_CONTAINER_UPDATED:
+		tag = "white_check_mark"
+	}
+	return []string{tag, string(event.Kind)}
+}
@@ -0,0 +1,23 @@
+package webhooks
+
+import yacuhook "github.com/terrails/yacu/types/webhook"
+
+type pushPriority int
+
+const (
+	PRIORITY_LOW pushPriority = iota
+	PRIORITY_DEFAULT
+	PRIORITY_HIGH
+)
+
+// failures are high priority, successful updates low
+func priorityOf(event *yacuhook.Event) pushPriority {
+	if event.IsFailure() {
+		return PRIORITY_HIGH
+	}
+	switch event.Kind {
+	case yacuhook.EVENT_IMAGE_UPDATED, yacuhook.EVENT_CONTAINER_UPDATED, yacuhook.EVENT_RUN_SUMMARY:
+		return PRIORITY_LOW
+	}
+	return PRIORITY_DEFAULT
+}
@@ -0,0 +1,191 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/terrails/yacu/types/config"
+	yacuhook "github.com/terrails/yacu/types/webhook"
+)
+
+// block kit limits of the slack api
+const (
+	slackHeaderLimit  = 150
+	slackSectionLimit = 3000
+	slackFieldLimit   = 2000
+	slackFieldCount   = 10
+)
+
+var markdownLink = regexp.MustCompile(`\[([^\]]+)\]\(([^)]+)\)`)
+
+// incoming webhook for slack, mattermost gets legacy attachments as it does not render blocks
+type SlackWebhook struct {
+	config    *config.Webhook
+	templates *yacuhook.Templates
+	legacy    bool
+}
+
+type slackPayload struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	IconUrl     string            `json:"icon_url,omitempty"`
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type slackAttachment struct {
+	Color     string       `json:"color"`
+	Fallback  string       `json:"fallback,omitempty"`
+	Blocks    []slackBlock `json:"blocks,omitempty"`
+	Title     string       `json:"title,omitempty"`
+	TitleLink string       `json:"title_link,omitempty"`
+	Text      string       `json:"text,omitempty"`
+	Fields    []slackField `json:"fields,omitempty"`
+	Footer    string       `json:"footer,omitempty"`
+	Timestamp int64        `json:"ts,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func SetupSlackWebhook(ctx context.Context, config *config.Webhook) (*SlackWebhook, error) {
+	return setupSlackWebhook(config, false)
+}
+
+func SetupMattermostWebhook(ctx context.Context, config *config.Webhook) (*SlackWebhook, error) {
+	return setupSlackWebhook(config, true)
+}
+
+func setupSlackWebhook(config *config.Webhook, legacy bool) (*SlackWebhook, error) {
+	for kind := range config.Channels {
+		if !yacuhook.IsEventKind(kind) {
+			return nil, fmt.Errorf("unknown event type %s in channels", kind)
+		}
+	}
+
+	templates, err := yacuhook.NewTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+	return &SlackWebhook{
+		config:    config,
+		templates: templates,
+		legacy:    legacy,
+	}, nil
+}
+
+func (hook *SlackWebhook) Name() string {
+	if hook.legacy {
+		return "mattermost"
+	}
+	return "slack"
+}
+
+func (hook *SlackWebhook) Send(ctx context.Context, event *yacuhook.Event) error {
+	message, err := hook.templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	channel := hook.config.Channel
+	if override, ok := hook.config.Channels[string(event.Kind)]; ok {
+		channel = override
+	}
+
+	payload := slackPayload{
+		Channel:  channel,
+		Username: hook.config.Author.Name,
+		IconUrl:  hook.config.Author.IconUrl,
+	}
+
+	attachment := slackAttachment{
+		Color:    fmt.Sprintf("#%06x", message.Color),
+		Fallback: message.Title,
+	}
+	if hook.legacy {
+		attachment.Title = message.Title
+		attachment.TitleLink = message.Url
+		attachment.Text = message.Description
+		attachment.Footer = "YACU by Terrails"
+		attachment.Timestamp = event.Time.Unix()
+		for _, field := range message.Fields {
+			attachment.Fields = append(attachment.Fields, slackField{Title: field.Name, Value: field.Value, Short: field.Inline})
+		}
+	} else {
+		payload.Text = message.Title
+		attachment.Blocks = slackBlocks(message)
+	}
+	payload.Attachments = []slackAttachment{attachment}
+
+	return postJson(ctx, hook.config.Url, payload, map[string]string{})
+}
+
+func slackBlocks(message *yacuhook.Message) []slackBlock {
+	blocks := []slackBlock{{
+		Type: "header",
+		Text: &slackText{Type: "plain_text", Text: truncate(message.Title, slackHeaderLimit)},
+	}}
+
+	if len(message.Description) > 0 {
+		blocks = append(blocks, slackSection(slackMarkdown(message.Description)))
+	}
+
+	// inline fields are shown side by side, the rest get a section each
+	inline := []slackText{}
+	for _, field := range message.Fields {
+		text := fmt.Sprintf("*%s*\n%s", slackMarkdown(field.Name), slackMarkdown(field.Value))
+		if field.Inline && len(inline) < slackFieldCount {
+			inline = append(inline, slackText{Type: "mrkdwn", Text: truncateLines(text, slackFieldLimit)})
+			continue
+		}
+		if len(inline) > 0 {
+			blocks = append(blocks, slackBlock{Type: "section", Fields: inline})
+			inline = []slackText{}
+		}
+		blocks = append(blocks, slackSection(text))
+	}
+	if len(inline) > 0 {
+		blocks = append(blocks, slackBlock{Type: "section", Fields: inline})
+	}
+
+	context := "YACU by Terrails"
+	if len(message.Url) > 0 {
+		context = fmt.Sprintf("<%s|Open> • %s", message.Url, context)
+	}
+	blocks = append(blocks, slackBlock{
+		Type:     "context",
+		Elements: []slackText{{Type: "mrkdwn", Text: context}},
+	})
+	return blocks
+}
+
+func slackSection(text string) slackBlock {
+	return slackBlock{
+		Type: "section",
+		Text: &slackText{Type: "mrkdwn", Text: truncateLines(text, slackSectionLimit)},
+	}
+}
+
+// converts the markdown of templates to slack mrkdwn
+func slackMarkdown(text string) string {
+	text = markdownLink.ReplaceAllString(text, "<$2|$1>")
+	text = strings.ReplaceAll(text, "**", "*")
+	return strings.ReplaceAll(text, "__", "_")
+}
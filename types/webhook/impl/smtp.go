@@ -0,0 +1,242 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/terrails/yacu/types/config"
+	yacuhook "github.com/terrails/yacu/types/webhook"
+)
+
+const (
+	SMTP_SECURITY_STARTTLS = "starttls"
+	SMTP_SECURITY_TLS      = "tls"
+	SMTP_SECURITY_NONE     = "none"
+)
+
+type SmtpWebhook struct {
+	config    *config.Webhook
+	templates *yacuhook.Templates
+	address   string
+}
+
+func SetupSmtpWebhook(ctx context.Context, config *config.Webhook) (*SmtpWebhook, error) {
+	if len(config.Host) == 0 {
+		return nil, fmt.Errorf("smtp host is not set")
+	}
+	if len(config.From) == 0 {
+		return nil, fmt.Errorf("smtp sender is not set")
+	}
+	if len(config.To) == 0 && len(config.Recipients) == 0 {
+		return nil, fmt.Errorf("smtp recipients are not set")
+	}
+	for kind := range config.Recipients {
+		if !yacuhook.IsEventKind(kind) {
+			return nil, fmt.Errorf("unknown event type %s in recipients", kind)
+		}
+	}
+
+	port := config.Port
+	switch strings.ToLower(config.Security) {
+	case "", SMTP_SECURITY_STARTTLS:
+		if port == 0 {
+			port = 587
+		}
+	case SMTP_SECURITY_TLS:
+		if port == 0 {
+			port = 465
+		}
+	case SMTP_SECURITY_NONE:
+		if port == 0 {
+			port = 25
+		}
+	default:
+		return nil, fmt.Errorf("unknown smtp security %s", config.Security)
+	}
+
+	templates, err := yacuhook.NewTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+	return &SmtpWebhook{
+		config:    config,
+		templates: templates,
+		address:   net.JoinHostPort(config.Host, strconv.Itoa(port)),
+	}, nil
+}
+
+func (hook *SmtpWebhook) Name() string {
+	return "smtp"
+}
+
+func (hook *SmtpWebhook) Send(ctx context.Context, event *yacuhook.Event) error {
+	recipients := hook.config.To
+	if override, ok := hook.config.Recipients[string(event.Kind)]; ok {
+		recipients = override
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	message, err := hook.templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	mail, err := buildMail(hook.config.From, recipients, event.Time, message)
+	if err != nil {
+		return err
+	}
+	return hook.sendMail(ctx, recipients, mail)
+}
+
+func (hook *SmtpWebhook) sendMail(ctx context.Context, recipients []string, mail []byte) error {
+	dialer := &net.Dialer{Timeout: time.Second * 30}
+	conn, err := dialer.DialContext(ctx, "tcp", hook.address)
+	if err != nil {
+		return err
+	}
+
+	security := strings.ToLower(hook.config.Security)
+	tlsConfig := &tls.Config{ServerName: hook.config.Host}
+	if security == SMTP_SECURITY_TLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	if ctxDeadline, ok := ctx.Deadline(); ok {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, hook.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if security == "" || security == SMTP_SECURITY_STARTTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starting tls failed: %w", err)
+		}
+	}
+
+	if len(hook.config.Username) > 0 {
+		if err := client.Auth(smtp.PlainAuth("", hook.config.Username, hook.config.Password, hook.config.Host)); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(hook.config.From); err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", recipient, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(mail); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// builds a multipart message with plain text and html bodies
+func buildMail(from string, to []string, date time.Time, message *yacuhook.Message) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", message.Markdown()},
+		{"text/html; charset=utf-8", mailHtml(message)},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(partWriter)
+		if _, err := encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var mail bytes.Buffer
+	headers := [][2]string{
+		{"From", from},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", message.Title)},
+		{"Date", date.Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%s", writer.Boundary())},
+	}
+	for _, header := range headers {
+		mail.WriteString(fmt.Sprintf("%s: %s\r\n", header[0], header[1]))
+	}
+	mail.WriteString("\r\n")
+	mail.Write(body.Bytes())
+	return mail.Bytes(), nil
+}
+
+func mailHtml(message *yacuhook.Message) string {
+	var builder strings.Builder
+
+	builder.WriteString("<html><body style=\"font-family: sans-serif;\">")
+	builder.WriteString(fmt.Sprintf("<h2 style=\"border-left: 4px solid #%06x; padding-left: 8px;\">%s</h2>", message.Color, html.EscapeString(message.Title)))
+
+	if len(message.Description) > 0 {
+		builder.WriteString(fmt.Sprintf("<div style=\"white-space: pre-wrap;\">%s</div>", markdownToHtml(message.Description)))
+	}
+
+	if len(message.Fields) > 0 {
+		builder.WriteString("<table style=\"margin-top: 12px; border-collapse: collapse;\">")
+		for _, field := range message.Fields {
+			builder.WriteString(fmt.Sprintf(
+				"<tr><th style=\"text-align: left; vertical-align: top; padding: 4px 12px 4px 0;\">%s</th><td style=\"white-space: pre-wrap; padding: 4px 0;\">%s</td></tr>",
+				markdownToHtml(field.Name), markdownToHtml(field.Value),
+			))
+		}
+		builder.WriteString("</table>")
+	}
+
+	if len(message.Url) > 0 {
+		url := html.EscapeString(message.Url)
+		builder.WriteString(fmt.Sprintf("<p><a href=\"%s\">%s</a></p>", url, url))
+	}
+
+	builder.WriteString("<p style=\"color: #888888; font-size: 12px;\">YACU by Terrails</p></body></html>")
+	return builder.String()
+}
@@ -0,0 +1,128 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/terrails/yacu/types/config"
+	yacuhook "github.com/terrails/yacu/types/webhook"
+)
+
+const (
+	TELEGRAM_FORMAT_HTML       = "html"
+	TELEGRAM_FORMAT_MARKDOWNV2 = "markdownv2"
+)
+
+// messages are limited to 4096 characters after escaping
+const telegramMessageLimit = 4096
+
+type TelegramWebhook struct {
+	config    *config.Webhook
+	templates *yacuhook.Templates
+	url       string
+}
+
+type telegramMessage struct {
+	ChatId                string `json:"chat_id"`
+	ThreadId              int    `json:"message_thread_id,omitempty"`
+	Text                  string `json:"text"`
+	ParseMode             string `json:"parse_mode"`
+	DisableNotification   bool   `json:"disable_notification"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+func SetupTelegramWebhook(ctx context.Context, config *config.Webhook) (*TelegramWebhook, error) {
+	if len(config.Token) == 0 {
+		return nil, fmt.Errorf("telegram bot token is not set")
+	}
+	if len(config.ChatId) == 0 && len(config.Chats) == 0 {
+		return nil, fmt.Errorf("telegram chat id is not set")
+	}
+	for kind := range config.Chats {
+		if !yacuhook.IsEventKind(kind) {
+			return nil, fmt.Errorf("unknown event type %s in chats", kind)
+		}
+	}
+
+	switch strings.ToLower(config.Format) {
+	case "", TELEGRAM_FORMAT_HTML, TELEGRAM_FORMAT_MARKDOWNV2:
+	default:
+		return nil, fmt.Errorf("unknown telegram format %s", config.Format)
+	}
+
+	templates, err := yacuhook.NewTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	// the url allows using a local bot api server
+	url := strings.TrimSuffix(config.Url, "/")
+	if len(url) == 0 {
+		url = "https://api.telegram.org"
+	}
+
+	return &TelegramWebhook{
+		config:    config,
+		templates: templates,
+		url:       fmt.Sprintf("%s/bot%s/sendMessage", url, config.Token),
+	}, nil
+}
+
+func (hook *TelegramWebhook) Name() string {
+	return "telegram"
+}
+
+func (hook *TelegramWebhook) Send(ctx context.Context, event *yacuhook.Event) error {
+	message, err := hook.templates.Render(event)
+	if err != nil {
+		return err
+	}
+
+	chat := config.WebhookChat{ChatId: hook.config.ChatId, ThreadId: hook.config.ThreadId}
+	if override, ok := hook.config.Chats[string(event.Kind)]; ok {
+		if len(override.ChatId) > 0 {
+			chat.ChatId = override.ChatId
+		}
+		chat.ThreadId = override.ThreadId
+	}
+	if len(chat.ChatId) == 0 {
+		return nil
+	}
+
+	parseMode, convert := "HTML", markdownToHtml
+	if strings.ToLower(hook.config.Format) == TELEGRAM_FORMAT_MARKDOWNV2 {
+		parseMode, convert = "MarkdownV2", markdownToTelegram
+	}
+
+	// only failures and pending approvals make a sound
+	silent := !event.IsFailure() && event.Kind != yacuhook.EVENT_UPDATE_PENDING
+
+	size := func(text string) int {
+		return utf8.RuneCountInString(convert(text))
+	}
+
+	parts := splitMarkdown(message.Markdown(), telegramMessageLimit, size)
+	for i := event.Part; i < len(parts); i++ {
+		if err := postJson(ctx, hook.url, telegramMessage{
+			ChatId:                chat.ChatId,
+			ThreadId:              chat.ThreadId,
+			Text:                  convert(parts[i]),
+			ParseMode:             parseMode,
+			DisableNotification:   silent,
+			DisableWebPagePreview: true,
+		}, map[string]string{}); err != nil {
+			// the token is part of the url
+			masked := errors.New(strings.ReplaceAll(err.Error(), hook.config.Token, "<token>"))
+			var retryAfter *yacuhook.RetryAfterError
+			if errors.As(err, &retryAfter) {
+				return &yacuhook.RetryAfterError{After: retryAfter.After, Err: masked}
+			}
+			return masked
+		}
+		event.Part = i + 1
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/database"
+)
+
+const (
+	retryBaseDelay     = time.Second * 30 // delay after the first failed attempt, doubled with every further one
+	retryMaxDelay      = time.Hour
+	outboxPollInterval = time.Minute
+	sendTimeout        = time.Minute
+)
+
+// returned by notifiers when the service asks to wait before sending again, e.g. on HTTP 429
+type RetryAfterError struct {
+	After time.Duration
+	Err   error
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+func (w *Webhooks) SetRetries(retries int) {
+	w.retries = retries
+}
+
+// stores the event for delivery by the outbox worker
+func (w *Webhooks) enqueue(hook webhook, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding event failed: %w", err)
+	}
+	if err := w.db.SaveOutbox(hook.name, string(event.Kind), string(data)); err != nil {
+		return fmt.Errorf("queueing notification failed: %w", err)
+	}
+
+	// wake the worker without waiting for it
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// starts delivering queued notifications in the background
+func (w *Webhooks) RunOutbox(ctx context.Context) {
+	go w.runOutbox(ctx)
+}
+
+func (w *Webhooks) runOutbox(ctx context.Context) {
+	logger := zerolog.Ctx(ctx)
+
+	for {
+		w.deliverOutbox(ctx)
+
+		wait := outboxPollInterval
+		if next, err := w.db.GetNextOutboxAttempt(); err != nil {
+			logger.Err(err).Msg("fetching next notification attempt failed")
+		} else if next != nil && time.Until(*next) < wait {
+			wait = max(time.Until(*next), time.Second)
+		}
+
+		select {
+		case <-w.wake:
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// sends the due notifications in order, a failing webhook is skipped until its next attempt
+func (w *Webhooks) deliverOutbox(ctx context.Context) {
+	logger := zerolog.Ctx(ctx)
+
+	rows, err := w.db.GetDueOutbox(time.Now())
+	if err != nil {
+		logger.Err(err).Msg("fetching queued notifications failed")
+		return
+	}
+
+	failed := map[string]bool{}
+	for _, row := range rows {
+		if failed[row.Webhook] {
+			continue
+		}
+		hookLogger := logger.With().Str("webhook", row.Webhook).Str("event", row.Kind).Int64("id", row.RowId).Logger()
+
+		if err := w.deliver(ctx, row); err != nil {
+			failed[row.Webhook] = true
+			w.retry(&hookLogger, row, err)
+			continue
+		}
+
+		if err := w.db.DeleteOutbox(row.RowId); err != nil {
+			hookLogger.Err(err).Msg("removing delivered notification failed")
+		}
+	}
+}
+
+func (w *Webhooks) deliver(ctx context.Context, row *database.OutboxRow) error {
+	var hook *webhook
+	for i := range w.webhooks {
+		if w.webhooks[i].name == row.Webhook {
+			hook = &w.webhooks[i]
+		}
+	}
+	if hook == nil {
+		return errors.New("webhook is not configured")
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(row.Event), &event); err != nil {
+		return fmt.Errorf("decoding event failed: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	if err := hook.funcs.Send(ctx, &event); err != nil {
+		// keep the progress of partially sent events for the next attempt
+		if data, merr := json.Marshal(event); merr == nil {
+			row.Event = string(data)
+		}
+		return err
+	}
+	return nil
+}
+
+// schedules the next attempt with exponential backoff unless the service asked for a delay, gives up once out of retries
+func (w *Webhooks) retry(logger *zerolog.Logger, row *database.OutboxRow, err error) {
+	row.Attempts++
+	row.LastError = err.Error()
+
+	delay := retryBaseDelay
+	for i := 1; i < row.Attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, retryMaxDelay)
+	var retryAfter *RetryAfterError
+	if errors.As(err, &retryAfter) && retryAfter.After > 0 {
+		delay = retryAfter.After
+	}
+	row.NextAttempt = time.Now().Add(delay)
+
+	if row.Attempts > w.retries {
+		row.Status = database.OUTBOX_FAILED
+		logger.Err(err).Int("attempts", row.Attempts).Msg("Sending notification failed, giving up")
+	} else {
+		logger.Warn().Err(err).Int("attempts", row.Attempts).Str("retry_in", delay.String()).Msg("Sending notification failed, retrying later")
+	}
+
+	if err := w.db.UpdateOutboxAttempt(row); err != nil {
+		logger.Err(err).Msg("updating queued notification failed")
+	}
+}
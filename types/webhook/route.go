@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/terrails/yacu/types/config"
+	"github.com/terrails/yacu/types/container"
+)
+
+const (
+	SEVERITY_ERROR   = "error"
+	SEVERITY_SUCCESS = "success"
+	SEVERITY_INFO    = "info"
+)
+
+// validates and sets the routing rules, webhooks not named by any route keep receiving every event.
+// Webhooks that are not set up, e.g. because their setup failed, are left out of the routes with a warning
+func (w *Webhooks) SetRoutes(ctx context.Context, routes config.Routes) error {
+	logger := zerolog.Ctx(ctx)
+
+	names := map[string]bool{}
+	for _, hook := range w.webhooks {
+		names[hook.name] = true
+	}
+
+	valid := config.Routes{}
+	routed := map[string]bool{}
+	for i, route := range routes {
+		for _, kind := range route.Match.Events {
+			if !IsEventKind(kind) {
+				return fmt.Errorf("route %d: unknown event type %s", i+1, kind)
+			}
+		}
+		for _, severity := range route.Match.Severity {
+			switch severity {
+			case SEVERITY_ERROR, SEVERITY_SUCCESS, SEVERITY_INFO:
+			default:
+				return fmt.Errorf("route %d: unknown severity %s", i+1, severity)
+			}
+		}
+		for _, pattern := range append(append(route.Match.Containers, route.Match.Projects...), route.Match.Images...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("route %d: invalid pattern %s", i+1, pattern)
+			}
+		}
+
+		notify := []string{}
+		for _, name := range route.Notify {
+			if !names[name] {
+				logger.Warn().Int("route", i+1).Str("webhook", name).Msg("Route names a webhook that is not set up, skipping it")
+				continue
+			}
+			notify = append(notify, name)
+			routed[name] = true
+		}
+		if len(notify) == 0 {
+			continue
+		}
+		route.Notify = notify
+		valid = append(valid, route)
+	}
+
+	w.routes = valid
+	w.routed = routed
+	return nil
+}
+
+// whether routing lets the event through to the named webhook
+func (w *Webhooks) routesTo(name string, event *Event) bool {
+	if !w.routed[name] {
+		return true
+	}
+
+	for _, route := range w.routes {
+		if contains(route.Notify, name) && routeMatch(route.Match).matches(event) {
+			return true
+		}
+	}
+	return false
+}
+
+type routeMatch config.RouteMatch
+
+func (m routeMatch) matches(event *Event) bool {
+	if len(m.Events) > 0 && !contains(m.Events, string(event.Kind)) {
+		return false
+	}
+	if len(m.Severity) > 0 && !contains(m.Severity, event.Severity()) {
+		return false
+	}
+
+	var labels map[string]string
+	name := ""
+	if event.Container != nil {
+		labels = event.Container.Labels
+		name = event.Container.Name
+	}
+
+	if len(m.Containers) > 0 && !matchesAny(m.Containers, name) {
+		return false
+	}
+	if len(m.Projects) > 0 && !matchesAny(m.Projects, labels[container.LABEL_COMPOSE_PROJECT]) {
+		return false
+	}
+	if len(m.Images) > 0 && (event.Image == nil || !matchesAny(m.Images, event.Image.Name)) {
+		return false
+	}
+	if len(m.Labels) > 0 {
+		found := false
+		for _, value := range strings.Split(labels[container.LABEL_NOTIFY], ",") {
+			if contains(m.Labels, strings.TrimSpace(value)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(patterns []string, value string) bool {
+	if len(value) == 0 {
+		return false
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, entry := range values {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
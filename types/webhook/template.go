@@ -0,0 +1,290 @@
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	"github.com/terrails/yacu/types/config"
+	"github.com/terrails/yacu/utils"
+)
+
+// rendered notification content, notifiers convert it into their own format
+type Message struct {
+	Title       string
+	Description string
+	Url         string
+	Color       int
+	Fields      []MessageField
+}
+
+type MessageField struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+type Templates struct {
+	templates map[EventKind]*eventTemplate
+}
+
+type eventTemplate struct {
+	title       *template.Template
+	description *template.Template
+	url         *template.Template
+	color       int
+	fields      []fieldTemplate
+}
+
+type fieldTemplate struct {
+	name   *template.Template
+	value  *template.Template
+	inline bool
+}
+
+var templateFuncs = template.FuncMap{
+	"shortId": func(id string) string {
+		if len(utils.IdEncoded(id)) < 12 {
+			return id
+		}
+		return utils.ShortId(id)
+	},
+	"encoded":          utils.IdEncoded,
+	"shortRevision":    utils.ShortRevision,
+	"humanizeDuration": utils.HumanizeDuration,
+	"humanizeBytes":    utils.HumanizeBytes,
+	"humanizeAge": func(t time.Time) string {
+		return utils.HumanizeDuration(time.Since(t))
+	},
+	"familiar": func(name string) string {
+		named, err := reference.ParseNormalizedNamed(name)
+		if err != nil {
+			return name
+		}
+		return reference.FamiliarString(named)
+	},
+	"versionChange": func(prev, new *ImageData) string {
+		if new == nil {
+			return ""
+		}
+		if prev == nil {
+			return new.Release.Version
+		}
+		return new.Release.VersionChange(prev.Release)
+	},
+	"compareUrl": func(prev, new *ImageData) string {
+		if prev == nil || new == nil {
+			return ""
+		}
+		return new.Release.CompareUrl(prev.Release)
+	},
+	"tail": func(max int, str string) string {
+		return utils.TailString(str, max)
+	},
+	"default": func(def, value string) string {
+		if len(strings.TrimSpace(value)) == 0 {
+			return def
+		}
+		return value
+	},
+	"join":  strings.Join,
+	"trim":  strings.TrimSpace,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// compiles the default templates overridden by the configured ones
+func NewTemplates(cfg config.WebhookTemplates) (*Templates, error) {
+	templates := &Templates{
+		templates: map[EventKind]*eventTemplate{},
+	}
+
+	for kind, def := range defaultTemplates {
+		tmpl := def
+		if custom, ok := cfg[string(kind)]; ok {
+			if err := mergeTemplate(&tmpl, custom); err != nil {
+				return nil, fmt.Errorf("reading %s template failed: %w", kind, err)
+			}
+		}
+
+		compiled, err := compileTemplate(kind, tmpl)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s template failed: %w", kind, err)
+		}
+		templates.templates[kind] = compiled
+	}
+
+	for kind := range cfg {
+		if !IsEventKind(kind) {
+			return nil, fmt.Errorf("unknown event type %s in templates", kind)
+		}
+	}
+
+	return templates, nil
+}
+
+func (t *Templates) Render(event *Event) (*Message, error) {
+	tmpl, ok := t.templates[event.Kind]
+	if !ok {
+		return nil, fmt.Errorf("no template for event type %s", event.Kind)
+	}
+
+	title, err := execute(tmpl.title, event)
+	if err != nil {
+		return nil, err
+	}
+
+	description, err := execute(tmpl.description, event)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := execute(tmpl.url, event)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &Message{
+		Title:       title,
+		Description: description,
+		Url:         url,
+		Color:       tmpl.color,
+		Fields:      []MessageField{},
+	}
+	if message.Color == 0 {
+		message.Color = defaultColor(event)
+	}
+
+	for _, field := range tmpl.fields {
+		name, err := execute(field.name, event)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := execute(field.value, event)
+		if err != nil {
+			return nil, err
+		}
+
+		// fields without a value are left out, e.g. unknown versions
+		if len(name) == 0 || len(value) == 0 {
+			continue
+		}
+		message.Fields = append(message.Fields, MessageField{Name: name, Value: value, Inline: field.inline})
+	}
+
+	return message, nil
+}
+
+// plain markdown version of the message for notifiers without structured messages
+func (m *Message) Markdown() string {
+	var builder strings.Builder
+
+	if len(m.Title) > 0 {
+		builder.WriteString(fmt.Sprintf("**%s**\n", m.Title))
+	}
+	if len(m.Description) > 0 {
+		builder.WriteString(m.Description)
+		builder.WriteString("\n")
+	}
+	for _, field := range m.Fields {
+		if strings.Contains(field.Value, "\n") {
+			builder.WriteString(fmt.Sprintf("**%s**\n%s\n", field.Name, field.Value))
+		} else {
+			builder.WriteString(fmt.Sprintf("**%s**: %s\n", field.Name, field.Value))
+		}
+	}
+	if len(m.Url) > 0 {
+		builder.WriteString(m.Url)
+		builder.WriteString("\n")
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// color of a run summary depends on its outcome
+func defaultColor(event *Event) int {
+	if event.Summary == nil {
+		return COLOR_NEUTRAL
+	}
+	if len(event.Summary.Failed) > 0 {
+		return COLOR_FAILURE
+	} else if len(event.Summary.Updated) > 0 {
+		return COLOR_SUCCESS
+	}
+	return COLOR_NEUTRAL
+}
+
+func mergeTemplate(tmpl *config.WebhookTemplate, custom config.WebhookTemplate) error {
+	if len(custom.File) > 0 {
+		file, err := os.ReadFile(custom.File)
+		if err != nil {
+			return err
+		}
+		tmpl.Description = string(file)
+	}
+	if len(custom.Title) > 0 {
+		tmpl.Title = custom.Title
+	}
+	if len(custom.Description) > 0 {
+		tmpl.Description = custom.Description
+	}
+	if len(custom.Url) > 0 {
+		tmpl.Url = custom.Url
+	}
+	if custom.Color != 0 {
+		tmpl.Color = custom.Color
+	}
+	// an empty list removes the default fields
+	if custom.Fields != nil {
+		tmpl.Fields = custom.Fields
+	}
+	return nil
+}
+
+func compileTemplate(kind EventKind, tmpl config.WebhookTemplate) (*eventTemplate, error) {
+	var err error
+	compiled := &eventTemplate{
+		color:  tmpl.Color,
+		fields: []fieldTemplate{},
+	}
+
+	if compiled.title, err = parse(string(kind)+".title", tmpl.Title); err != nil {
+		return nil, err
+	}
+	if compiled.description, err = parse(string(kind)+".description", tmpl.Description); err != nil {
+		return nil, err
+	}
+	if compiled.url, err = parse(string(kind)+".url", tmpl.Url); err != nil {
+		return nil, err
+	}
+
+	for i, field := range tmpl.Fields {
+		name, err := parse(fmt.Sprintf("%s.fields[%d].name", kind, i), field.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parse(fmt.Sprintf("%s.fields[%d].value", kind, i), field.Value)
+		if err != nil {
+			return nil, err
+		}
+		compiled.fields = append(compiled.fields, fieldTemplate{name: name, value: value, inline: field.Inline})
+	}
+
+	return compiled, nil
+}
+
+func parse(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+func execute(tmpl *template.Template, event *Event) (string, error) {
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, event); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buffer.String()), nil
+}
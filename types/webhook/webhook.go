@@ -2,93 +2,209 @@ package webhook
 
 import (
 	"context"
+	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/terrails/yacu/types/config"
 	"github.com/terrails/yacu/types/container"
+	"github.com/terrails/yacu/types/database"
 	"github.com/terrails/yacu/types/image"
+	"github.com/terrails/yacu/types/summary"
 )
 
-type webhookFuncs interface {
-	Error(ctx context.Context, context string, err error)
-
-	ImageUpdated(ctx context.Context, prevImage, newImage *image.ImageData)
-	ImageError(ctx context.Context, image *image.ImageData, context string, err error)
-	ImageRemovalFailed(ctx context.Context, image *image.ImageData, err error)
-
-	ContainerUpdated(ctx context.Context, prevContainer, newContainer *container.Container, warnings ...string)
-	ContainerError(ctx context.Context, container *container.Container, context string, err error)
+// implemented by every notification service
+type Notifier interface {
+	// service of the notifier
+	Name() string
+	Send(ctx context.Context, event *Event) error
 }
 
 type webhook struct {
-	funcs             webhookFuncs
+	name              string
+	funcs             Notifier
 	errors            bool
 	image_success     bool
 	container_success bool
+	pending           bool
+	events            bool
+	summary           bool
+	digest            string // cron expression of digests, empty if not collected
 }
 
 type Webhooks struct {
 	webhooks []webhook
+	db       database.Database
+	routes   config.Routes
+	routed   map[string]bool // webhooks named by routes
+
+	dedupPeriod time.Duration // repeated errors are suppressed for this long, 0 disables deduplication
+	resolved    bool          // notify when a deduplicated error stops occurring
+
+	retries int           // failed deliveries are retried this many times
+	wake    chan struct{} // signals the outbox worker about queued notifications
 }
 
-func NewWebhookHandler() *Webhooks {
+func NewWebhookHandler(db database.Database) *Webhooks {
 	return &Webhooks{
 		webhooks: []webhook{},
+		db:       db,
+		routes:   config.Routes{},
+		routed:   map[string]bool{},
+		wake:     make(chan struct{}, 1),
 	}
 }
 
-func (w *Webhooks) Append(hook webhookFuncs, config *config.WebhookKind) {
+func (w *Webhooks) Append(name string, hook Notifier, settings *config.Webhook) {
+	// per event notifications unless configured otherwise
+	mode := settings.Mode
+	if len(mode) == 0 {
+		mode = config.WEBHOOK_MODE_EVENT
+	}
+
+	digest := ""
+	if mode == config.WEBHOOK_MODE_DIGEST {
+		digest = settings.DigestSchedule()
+	}
+
 	w.webhooks = append(w.webhooks, webhook{
+		name:              name,
 		funcs:             hook,
-		errors:            *config.Errors,
-		image_success:     *config.ImageSuccess,
-		container_success: *config.ContainerSuccess,
+		errors:            *settings.Kind.Errors,
+		image_success:     *settings.Kind.ImageSuccess,
+		container_success: *settings.Kind.ContainerSuccess,
+		pending:           *settings.Kind.Pending,
+		events:            mode == config.WEBHOOK_MODE_EVENT || mode == config.WEBHOOK_MODE_BOTH,
+		summary:           mode == config.WEBHOOK_MODE_SUMMARY || mode == config.WEBHOOK_MODE_BOTH,
+		digest:            digest,
 	})
 }
 
-func (w *Webhooks) Error(ctx context.Context, context string, err error) {
-	for _, hook := range w.webhooks {
-		if hook.errors {
-			hook.funcs.Error(ctx, context, err)
-		}
+func (hook webhook) accepts(kind EventKind) bool {
+	switch kind {
+	case EVENT_RUN_SUMMARY:
+		return hook.summary
+	case EVENT_DIGEST:
+		return len(hook.digest) > 0
+	case EVENT_IMAGE_UPDATED:
+		return hook.events && hook.image_success
+	case EVENT_CONTAINER_UPDATED:
+		return hook.events && hook.container_success
+	case EVENT_UPDATE_PENDING:
+		return hook.events && hook.pending
+	case EVENT_RESOLVED:
+		return hook.events && hook.errors
+	default:
+		return hook.events && hook.errors
 	}
 }
 
-func (w *Webhooks) ImageUpdated(ctx context.Context, prevImage, newImage *image.ImageData) {
+// queues the event for every webhook that accepts it
+func (w *Webhooks) dispatch(ctx context.Context, event *Event) {
+	logger := zerolog.Ctx(ctx)
+
+	if !w.deduplicate(ctx, event) {
+		logger.Debug().Str("event", string(event.Kind)).Str("context", event.Context).Msg("Repeated error not sent")
+		return
+	}
+
 	for _, hook := range w.webhooks {
-		if hook.image_success {
-			hook.funcs.ImageUpdated(ctx, prevImage, newImage)
+		if !hook.accepts(event.Kind) || !w.routesTo(hook.name, event) {
+			continue
+		}
+		if err := w.enqueue(hook, event); err != nil {
+			logger.Err(err).Str("webhook", hook.name).Str("service", hook.funcs.Name()).Str("event", string(event.Kind)).Msg("Encountered an error while sending a webhook")
 		}
 	}
 }
 
+func (w *Webhooks) Error(ctx context.Context, context string, err error) {
+	event := newEvent(EVENT_ERROR)
+	event.Context = context
+	event.Error = errorString(err)
+	w.dispatch(ctx, event)
+}
+
+func (w *Webhooks) ImageUpdated(ctx context.Context, prevImage, newImage *image.ImageData) {
+	event := newEvent(EVENT_IMAGE_UPDATED)
+	event.Image = newImageData(newImage)
+	event.PrevImage = newImageData(prevImage)
+	event.Registry = registryOf(newImage.Repository)
+	w.dispatch(ctx, event)
+}
+
 func (w *Webhooks) ImageError(ctx context.Context, image *image.ImageData, context string, err error) {
-	for _, hook := range w.webhooks {
-		if hook.errors {
-			hook.funcs.ImageError(ctx, image, context, err)
-		}
-	}
+	event := newEvent(EVENT_IMAGE_ERROR)
+	event.Context = context
+	event.Error = errorString(err)
+	event.Image = newImageData(image)
+	event.Registry = registryOf(image.Repository)
+	w.dispatch(ctx, event)
 }
 
 func (w *Webhooks) ImageRemovalFailed(ctx context.Context, image *image.ImageData, err error) {
-	for _, hook := range w.webhooks {
-		if hook.errors {
-			hook.funcs.ImageRemovalFailed(ctx, image, err)
-		}
-	}
+	event := newEvent(EVENT_IMAGE_REMOVAL_FAILED)
+	event.Error = errorString(err)
+	event.Image = newImageData(image)
+	event.Registry = registryOf(image.Repository)
+	w.dispatch(ctx, event)
 }
 
-func (w *Webhooks) ContainerUpdated(ctx context.Context, prevContainer, newContainer *container.Container, warnings ...string) {
-	for _, hook := range w.webhooks {
-		if hook.container_success {
-			hook.funcs.ContainerUpdated(ctx, prevContainer, newContainer, warnings...)
-		}
+func (w *Webhooks) ContainerUpdated(ctx context.Context, prevContainer, newContainer *container.Container, hooks []*container.HookResult, warnings ...string) {
+	event := newEvent(EVENT_CONTAINER_UPDATED)
+	event.Warnings = warnings
+	event.Container = newContainerData(newContainer)
+	event.PrevContainer = newContainerData(prevContainer)
+	event.Image = event.Container.Image
+	event.PrevImage = event.PrevContainer.Image
+	event.Registry = registryOf(newContainer.Repository)
+	for _, result := range hooks {
+		event.Hooks = append(event.Hooks, result.HookOutput())
 	}
+	w.dispatch(ctx, event)
 }
 
 func (w *Webhooks) ContainerError(ctx context.Context, container *container.Container, context string, err error) {
-	for _, hook := range w.webhooks {
-		if hook.errors {
-			hook.funcs.ContainerError(ctx, container, context, err)
-		}
+	event := newEvent(EVENT_CONTAINER_ERROR)
+	event.Context = context
+	event.Error = errorString(err)
+	event.Container = newContainerData(container)
+	event.Image = event.Container.Image
+	event.Registry = registryOf(container.Repository)
+	w.dispatch(ctx, event)
+}
+
+func (w *Webhooks) ContainerQuarantined(ctx context.Context, container *container.Container, failure *database.UpdateFailureRow) {
+	event := newEvent(EVENT_CONTAINER_QUARANTINED)
+	event.Context = "Update failed repeatedly, container quarantined"
+	event.Error = failure.LastError
+	event.Container = newContainerData(container)
+	event.Image = event.Container.Image
+	event.Registry = registryOf(container.Repository)
+	event.Quarantine = &QuarantineData{
+		Digest:      failure.Digest.String(),
+		Failures:    failure.Count,
+		FirstFailed: failure.FirstFailed,
+	}
+	w.dispatch(ctx, event)
+}
+
+func (w *Webhooks) UpdatePending(ctx context.Context, container *container.Container, pending *database.PendingUpdateRow) {
+	event := newEvent(EVENT_UPDATE_PENDING)
+	event.Container = newContainerData(container)
+	event.Image = event.Container.Image
+	event.Registry = registryOf(container.Repository)
+	event.Pending = &PendingData{
+		Token:  pending.Token,
+		Digest: pending.Digest.String(),
 	}
+	w.dispatch(ctx, event)
+}
+
+func (w *Webhooks) RunSummary(ctx context.Context, summary *summary.Summary) {
+	event := newEvent(EVENT_RUN_SUMMARY)
+	event.Summary = summary
+	event.Warnings = summary.Warnings()
+	w.dispatch(ctx, event)
+
+	w.collectDigests(ctx, summary)
 }
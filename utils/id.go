@@ -20,3 +20,11 @@ func IdEncoded(id string) string {
 		return id
 	}
 }
+
+// shortens git commit hashes, leaves tags and other short revisions intact
+func ShortRevision(revision string) string {
+	if len(revision) > 12 {
+		return revision[:7]
+	}
+	return revision
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/docker/distribution/reference"
 )
@@ -9,3 +10,20 @@ import (
 func FamiliarTagged(repository reference.NamedTagged) string {
 	return fmt.Sprintf("%s:%s", reference.FamiliarName(repository), repository.Tag())
 }
+
+// web page of the repository for the registries that have one, empty otherwise
+func RegistryPageUrl(repository reference.Named) string {
+	path := reference.Path(repository)
+	switch reference.Domain(repository) {
+	case "docker.io":
+		if strings.HasPrefix(path, "library/") {
+			return fmt.Sprintf("https://hub.docker.com/_/%s", strings.TrimPrefix(path, "library/"))
+		}
+		return fmt.Sprintf("https://hub.docker.com/r/%s", path)
+	case "ghcr.io":
+		return fmt.Sprintf("https://ghcr.io/%s", path)
+	case "quay.io":
+		return fmt.Sprintf("https://quay.io/repository/%s", path)
+	}
+	return ""
+}
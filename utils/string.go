@@ -0,0 +1,10 @@
+package utils
+
+// keeps the last max characters, used for command output where the end is the most relevant part
+func TailString(str string, max int) string {
+	runes := []rune(str)
+	if len(runes) <= max {
+		return str
+	}
+	return "…" + string(runes[len(runes)-max+1:])
+}